@@ -0,0 +1,426 @@
+package jsonlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Builder constructs Value trees from Go data, without parsing JSON text.
+//
+// The values produced by a Builder are identical to the values returned by
+// Parse for their compact JSON text, which they hold as well: JSON returns it,
+// and Lookup works on the objects they contain. Values added with Raw keep
+// their original text.
+//
+// Arrays and objects are built by callbacks, which add the elements or fields
+// to the ArrayBuilder or ObjectBuilder they receive; nested containers are
+// built by nested callbacks. The builders given to the callbacks must not be
+// used after the callbacks return, and values must always be added to the
+// innermost container being built.
+//
+// The zero value is ready to use. A Builder can be reused to build multiple
+// values, which share its memory; it must not be used concurrently.
+type Builder struct {
+	// buf holds the JSON text of the values being built. It is only ever
+	// appended to, so the values can reference their text in it even after
+	// it was reallocated.
+	buf []byte
+	// values and fields are the scratch space of the arrays and objects
+	// being built, each using the tail of the slices.
+	values []Value
+	fields []field
+	// scope identifies the innermost container being built, 0 if none.
+	scope  int
+	scopes int
+}
+
+// Null returns a JSON null value.
+func (b *Builder) Null() *Value { return b.root(b.null()) }
+
+// Bool returns a JSON boolean value.
+func (b *Builder) Bool(v bool) *Value { return b.root(b.bool(v)) }
+
+// Int returns a JSON number holding a signed integer.
+func (b *Builder) Int(n int64) *Value { return b.root(b.int(n)) }
+
+// Uint returns a JSON number holding an unsigned integer.
+func (b *Builder) Uint(n uint64) *Value { return b.root(b.uint(n)) }
+
+// Float returns a JSON number holding a floating-point number. Integral values
+// are written with a fractional part, such as 2.0, so their NumberType is
+// Float. Panics if f is NaN or infinite, which cannot be represented in JSON.
+func (b *Builder) Float(f float64) *Value { return b.root(b.float(f, 64)) }
+
+// String returns a JSON string.
+func (b *Builder) String(s string) *Value { return b.root(b.string(s)) }
+
+// Raw returns a copy of v, which shares its memory with v. It returns a JSON
+// null value if v is nil.
+func (b *Builder) Raw(v *Value) *Value { return b.root(b.raw(v)) }
+
+// Array returns a JSON array holding the elements added by fn.
+func (b *Builder) Array(fn func(*ArrayBuilder)) *Value { return b.root(b.array(fn)) }
+
+// Object returns a JSON object holding the fields added by fn, in the order
+// they were added.
+func (b *Builder) Object(fn func(*ObjectBuilder)) *Value { return b.root(b.object(fn)) }
+
+// root returns the root value v, which was just built.
+func (b *Builder) root(v Value) *Value {
+	if b.scope != 0 {
+		panic("jsonlite: Builder used while building an array or object")
+	}
+	// The text of the next value is appended after the text of v, leaving it
+	// unchanged.
+	b.buf = b.buf[len(b.buf):]
+	return &v
+}
+
+// text returns the JSON text appended to b.buf from start.
+func (b *Builder) text(start int) string {
+	return unsafe.String(&b.buf[start], len(b.buf)-start)
+}
+
+func (b *Builder) null() Value {
+	start := len(b.buf)
+	b.buf = AppendNull(b.buf)
+	return makeNullValue(b.text(start))
+}
+
+func (b *Builder) bool(v bool) Value {
+	start := len(b.buf)
+	b.buf = AppendBool(b.buf, v)
+	if v {
+		return makeTrueValue(b.text(start))
+	}
+	return makeFalseValue(b.text(start))
+}
+
+func (b *Builder) int(n int64) Value {
+	start := len(b.buf)
+	b.buf = AppendInt(b.buf, n)
+	return makeNumberValue(b.text(start))
+}
+
+func (b *Builder) uint(n uint64) Value {
+	start := len(b.buf)
+	b.buf = AppendUint(b.buf, n)
+	return makeNumberValue(b.text(start))
+}
+
+// float appends the floating-point number f, formatted with the given bit
+// size.
+func (b *Builder) float(f float64, bitSize int) Value {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		panic(fmt.Sprintf("jsonlite: Float called with %v", f))
+	}
+	start := len(b.buf)
+	b.buf = strconv.AppendFloat(b.buf, f, 'g', -1, bitSize)
+	if NumberTypeOf(b.text(start)) != Float {
+		b.buf = append(b.buf, ".0"...)
+	}
+	return makeNumberValue(b.text(start))
+}
+
+func (b *Builder) string(s string) Value {
+	start := len(b.buf)
+	b.buf = AppendQuote(b.buf, s)
+	return makeStringValue(b.text(start))
+}
+
+func (b *Builder) raw(v *Value) Value {
+	if v == nil {
+		return b.null()
+	}
+	b.buf = append(b.buf, v.JSON()...)
+	return *v
+}
+
+// open starts building a container, returning the scope of its builder and
+// the scope of the parent.
+func (b *Builder) open(c byte) (scope, parent int) {
+	b.buf = append(b.buf, c)
+	b.scopes++
+	parent, b.scope = b.scope, b.scopes
+	return b.scope, parent
+}
+
+func (b *Builder) array(fn func(*ArrayBuilder)) Value {
+	start := len(b.buf)
+	a := ArrayBuilder{b: b, base: len(b.values)}
+	var parent int
+	a.scope, parent = b.open('[')
+	fn(&a)
+	b.scope = parent
+	b.buf = append(b.buf, ']')
+
+	elements := b.values[a.base:]
+	result := make([]Value, len(elements)+1)
+	result[0] = makeStringValue(b.text(start))
+	copy(result[1:], elements)
+
+	// Clear the scratch space so the builder does not retain the values.
+	clear(elements)
+	b.values = b.values[:a.base]
+	return makeArrayValue(result)
+}
+
+func (b *Builder) object(fn func(*ObjectBuilder)) Value {
+	start := len(b.buf)
+	o := ObjectBuilder{b: b, base: len(b.fields)}
+	var parent int
+	o.scope, parent = b.open('{')
+	fn(&o)
+	b.scope = parent
+	b.buf = append(b.buf, '}')
+
+	fields := b.fields[o.base:]
+	result := make([]field, len(fields)+1)
+	copy(result[1:], fields)
+	hashes := make([]byte, len(fields))
+	for i := range fields {
+		hashes[i] = byte(maphash.String(hashseed, fields[i].k))
+	}
+	result[0].v = makeStringValue(b.text(start))
+	result[0].k = unsafe.String(unsafe.SliceData(hashes), len(hashes))
+
+	clear(fields)
+	b.fields = b.fields[:o.base]
+	return makeObjectValue(result)
+}
+
+// ArrayBuilder adds elements to an array being built by a Builder.
+type ArrayBuilder struct {
+	b     *Builder
+	base  int // index of the first element in b.values
+	scope int
+}
+
+// next prepares the addition of an element, returning the builder which
+// appends it.
+func (a *ArrayBuilder) next() *Builder {
+	if a.scope != a.b.scope {
+		panic("jsonlite: ArrayBuilder used outside of the innermost array being built")
+	}
+	if len(a.b.values) > a.base {
+		a.b.buf = append(a.b.buf, ',')
+	}
+	return a.b
+}
+
+func (a *ArrayBuilder) push(v Value) { a.b.values = append(a.b.values, v) }
+
+// Len returns the number of elements added to the array.
+func (a *ArrayBuilder) Len() int { return len(a.b.values) - a.base }
+
+// Null adds a JSON null to the array.
+func (a *ArrayBuilder) Null() { a.push(a.next().null()) }
+
+// Bool adds a JSON boolean to the array.
+func (a *ArrayBuilder) Bool(v bool) { a.push(a.next().bool(v)) }
+
+// Int adds a JSON number holding a signed integer to the array.
+func (a *ArrayBuilder) Int(n int64) { a.push(a.next().int(n)) }
+
+// Uint adds a JSON number holding an unsigned integer to the array.
+func (a *ArrayBuilder) Uint(n uint64) { a.push(a.next().uint(n)) }
+
+// Float adds a JSON number holding a floating-point number to the array, like
+// Builder.Float.
+func (a *ArrayBuilder) Float(f float64) { a.push(a.next().float(f, 64)) }
+
+// String adds a JSON string to the array.
+func (a *ArrayBuilder) String(s string) { a.push(a.next().string(s)) }
+
+// Raw adds a copy of v to the array, or a JSON null if v is nil.
+func (a *ArrayBuilder) Raw(v *Value) { a.push(a.next().raw(v)) }
+
+// Array adds an array holding the elements added by fn to the array.
+func (a *ArrayBuilder) Array(fn func(*ArrayBuilder)) { a.push(a.next().array(fn)) }
+
+// Object adds an object holding the fields added by fn to the array.
+func (a *ArrayBuilder) Object(fn func(*ObjectBuilder)) { a.push(a.next().object(fn)) }
+
+// ObjectBuilder adds fields to an object being built by a Builder.
+type ObjectBuilder struct {
+	b     *Builder
+	base  int // index of the first field in b.fields
+	scope int
+}
+
+// key prepares the addition of a field with the given key, returning the
+// builder which appends its value.
+func (o *ObjectBuilder) key(k string) *Builder {
+	if o.scope != o.b.scope {
+		panic("jsonlite: ObjectBuilder used outside of the innermost object being built")
+	}
+	if len(o.b.fields) > o.base {
+		o.b.buf = append(o.b.buf, ',')
+	}
+	o.b.buf = AppendQuote(o.b.buf, k)
+	o.b.buf = append(o.b.buf, ':')
+	return o.b
+}
+
+func (o *ObjectBuilder) push(k string, v Value) { o.b.fields = append(o.b.fields, field{k: k, v: v}) }
+
+// Len returns the number of fields added to the object.
+func (o *ObjectBuilder) Len() int { return len(o.b.fields) - o.base }
+
+// Null adds a field holding a JSON null to the object.
+func (o *ObjectBuilder) Null(k string) { o.push(k, o.key(k).null()) }
+
+// Bool adds a field holding a JSON boolean to the object.
+func (o *ObjectBuilder) Bool(k string, v bool) { o.push(k, o.key(k).bool(v)) }
+
+// Int adds a field holding a signed integer to the object.
+func (o *ObjectBuilder) Int(k string, n int64) { o.push(k, o.key(k).int(n)) }
+
+// Uint adds a field holding an unsigned integer to the object.
+func (o *ObjectBuilder) Uint(k string, n uint64) { o.push(k, o.key(k).uint(n)) }
+
+// Float adds a field holding a floating-point number to the object, like
+// Builder.Float.
+func (o *ObjectBuilder) Float(k string, f float64) { o.push(k, o.key(k).float(f, 64)) }
+
+// String adds a field holding a JSON string to the object.
+func (o *ObjectBuilder) String(k string, s string) { o.push(k, o.key(k).string(s)) }
+
+// Raw adds a field holding a copy of v to the object, or a JSON null if v is
+// nil.
+func (o *ObjectBuilder) Raw(k string, v *Value) { o.push(k, o.key(k).raw(v)) }
+
+// Array adds a field holding an array with the elements added by fn to the
+// object.
+func (o *ObjectBuilder) Array(k string, fn func(*ArrayBuilder)) { o.push(k, o.key(k).array(fn)) }
+
+// Object adds a field holding an object with the fields added by fn to the
+// object.
+func (o *ObjectBuilder) Object(k string, fn func(*ObjectBuilder)) { o.push(k, o.key(k).object(fn)) }
+
+// ValueOf returns the Value representing x. It is the inverse of As[any]:
+// As[any](v) is equal to x for the Go values returned by As[any], which are
+// nil, bool, int64, uint64, float64, string, []any and map[string]any, nested
+// in any combination. The keys of maps are sorted.
+//
+// Other integer and floating-point types, json.Number and *Value are supported
+// as well. Values of any other type are encoded with the rules of Marshal; a
+// *MarshalError is returned if x holds a value which cannot be encoded, such
+// as a NaN.
+func ValueOf(x any) (*Value, error) {
+	var b Builder
+	v, err := b.valueOf(x)
+	if err != nil {
+		return nil, resolvePath(err)
+	}
+	return b.root(v), nil
+}
+
+func (b *Builder) valueOf(x any) (Value, error) {
+	switch x := x.(type) {
+	case nil:
+		return b.null(), nil
+	case bool:
+		return b.bool(x), nil
+	case int:
+		return b.int(int64(x)), nil
+	case int8:
+		return b.int(int64(x)), nil
+	case int16:
+		return b.int(int64(x)), nil
+	case int32:
+		return b.int(int64(x)), nil
+	case int64:
+		return b.int(x), nil
+	case uint:
+		return b.uint(uint64(x)), nil
+	case uint8:
+		return b.uint(uint64(x)), nil
+	case uint16:
+		return b.uint(uint64(x)), nil
+	case uint32:
+		return b.uint(uint64(x)), nil
+	case uint64:
+		return b.uint(x), nil
+	case float32:
+		return b.valueOfFloat(float64(x), 32)
+	case float64:
+		return b.valueOfFloat(x, 64)
+	case string:
+		return b.string(x), nil
+	case json.Number:
+		if !validNumber(string(x)) {
+			return Value{}, marshalError(numberType, fmt.Errorf("%w: invalid number %q", ErrUnsupportedValue, x))
+		}
+		start := len(b.buf)
+		b.buf = append(b.buf, x...)
+		return makeNumberValue(b.text(start)), nil
+	case *Value:
+		return b.raw(x), nil
+	case []any:
+		var err error
+		v := b.array(func(a *ArrayBuilder) {
+			for i, elem := range x {
+				a.next()
+				var v Value
+				if v, err = b.valueOf(elem); err != nil {
+					err = prependIndex(err, i)
+					return
+				}
+				a.push(v)
+			}
+		})
+		return v, err
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		slices.SortFunc(keys, strings.Compare)
+		var err error
+		v := b.object(func(o *ObjectBuilder) {
+			for _, k := range keys {
+				o.key(k)
+				var v Value
+				if v, err = b.valueOf(x[k]); err != nil {
+					err = prependKey(err, k)
+					return
+				}
+				o.push(k, v)
+			}
+		})
+		return v, err
+	default:
+		// The value is encoded and parsed, its text being kept in b.buf.
+		rv := reflect.ValueOf(x)
+		start := len(b.buf)
+		buf, err := encoderOf(rv.Type())(encodeState{}, b.buf, rv)
+		if err != nil {
+			return Value{}, err
+		}
+		b.buf = buf
+		v, err := Parse(b.text(start))
+		if err != nil {
+			return Value{}, err
+		}
+		return *v, nil
+	}
+}
+
+func (b *Builder) valueOfFloat(f float64, bitSize int) (Value, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		t := reflect.TypeFor[float64]()
+		if bitSize == 32 {
+			t = reflect.TypeFor[float32]()
+		}
+		return Value{}, marshalError(t, fmt.Errorf("%w: %v", ErrUnsupportedValue, f))
+	}
+	return b.float(f, bitSize), nil
+}
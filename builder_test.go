@@ -0,0 +1,204 @@
+package jsonlite_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestBuilder(t *testing.T) {
+	var b jsonlite.Builder
+	raw, _ := jsonlite.Parse(`{ "x" : [1, 2] }`)
+	pair, _ := jsonlite.Parse(`[1, 2]`)
+	v := b.Object(func(o *jsonlite.ObjectBuilder) {
+		o.String("name", "café \"x\"")
+		o.Int("int", -1)
+		o.Uint("uint", math.MaxUint64)
+		o.Float("float", 2)
+		o.Bool("ok", true)
+		o.Null("none")
+		o.Raw("raw", raw)
+		o.Raw("nil", nil)
+		o.Array("list", func(a *jsonlite.ArrayBuilder) {
+			a.Int(1)
+			a.Float(0.5)
+			a.String("s")
+			a.Bool(false)
+			a.Null()
+			a.Raw(pair)
+			a.Array(func(*jsonlite.ArrayBuilder) {})
+			a.Object(func(o *jsonlite.ObjectBuilder) { o.Int("deep", 3) })
+			if a.Len() != 8 {
+				t.Errorf("expected 8 elements, got %d", a.Len())
+			}
+		})
+		o.Object("empty", func(*jsonlite.ObjectBuilder) {})
+	})
+
+	const want = `{"name":"café \"x\"","int":-1,"uint":18446744073709551615,"float":2.0,"ok":true,"none":null,` +
+		`"raw":{ "x" : [1, 2] },"nil":null,"list":[1,0.5,"s",false,null,[1, 2],[],{"deep":3}],"empty":{}}`
+	if v.JSON() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, v.JSON())
+	}
+
+	// The tree is identical to the one parsed from its JSON text.
+	parsed, err := jsonlite.Parse(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(jsonlite.As[any](v), jsonlite.As[any](parsed)) {
+		t.Errorf("expected %v, got %v", jsonlite.As[any](parsed), jsonlite.As[any](v))
+	}
+	if v.Lookup("name").String() != "café \"x\"" || v.Lookup("uint").Uint() != math.MaxUint64 || v.Lookup("missing") != nil {
+		t.Error("unexpected lookup results")
+	}
+	if n := v.Lookup("float"); n.NumberType() != jsonlite.Float || n.Float() != 2 {
+		t.Errorf("expected float 2.0, got %s", n.JSON())
+	}
+	list := v.Lookup("list")
+	if list.Len() != 8 || list.Index(5).JSON() != `[1, 2]` || list.Index(7).Lookup("deep").Int() != 3 {
+		t.Errorf("unexpected list %s", list.JSON())
+	}
+	if got := string(v.Compact(nil)); got != string(parsed.Compact(nil)) {
+		t.Errorf("expected compact JSON %s, got %s", parsed.Compact(nil), got)
+	}
+}
+
+func TestBuilderReuse(t *testing.T) {
+	// Values built by the same builder remain valid.
+	var b jsonlite.Builder
+	var values []*jsonlite.Value
+	for i := range 100 {
+		values = append(values, b.Array(func(a *jsonlite.ArrayBuilder) {
+			for j := range i {
+				a.Int(int64(j))
+			}
+		}))
+		values = append(values, b.String("s"))
+	}
+	for i := range 100 {
+		if v := values[2*i]; v.Len() != i || (i > 0 && v.Index(i-1).Int() != int64(i-1)) || !jsonlite.Valid(v.JSON()) {
+			t.Fatalf("unexpected value %d: %s", i, v.JSON())
+		}
+		if v := values[2*i+1]; v.String() != "s" {
+			t.Fatalf("unexpected value: %s", v.JSON())
+		}
+	}
+	if b.Null().JSON() != "null" || b.Bool(false).JSON() != "false" || b.Int(-3).JSON() != "-3" ||
+		b.Uint(3).JSON() != "3" || b.Float(1e21).JSON() != "1e+21" || b.Raw(nil).JSON() != "null" {
+		t.Error("unexpected scalar values")
+	}
+}
+
+func TestBuilderMisuse(t *testing.T) {
+	var b jsonlite.Builder
+	var outer *jsonlite.ArrayBuilder
+	tests := map[string]func(){
+		"NaN": func() { b.Float(math.NaN()) },
+		"outer": func() {
+			b.Array(func(a *jsonlite.ArrayBuilder) {
+				a.Array(func(*jsonlite.ArrayBuilder) { a.Int(1) })
+			})
+		},
+		"after": func() {
+			b.Array(func(a *jsonlite.ArrayBuilder) { outer = a })
+			outer.Int(1)
+		},
+		"builder": func() {
+			b.Object(func(o *jsonlite.ObjectBuilder) { b.Int(1) })
+		},
+	}
+	for name, fn := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			b = jsonlite.Builder{}
+			fn()
+		})
+	}
+}
+
+func TestValueOf(t *testing.T) {
+	// ValueOf is the inverse of As[any].
+	input := `{"a": [1, -2, 18446744073709551615, 2.5, 3.0, 1e+21, "s", true, false, null], "b": {"c": {}, "d": []}, "": "empty"}`
+	parsed, err := jsonlite.Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := jsonlite.As[any](parsed)
+	v, err := jsonlite.ValueOf(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := jsonlite.As[any](v); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %#v, got %#v", want, got)
+	}
+	if v.JSON() != `{"":"empty","a":[1,-2,18446744073709551615,2.5,3.0,1e+21,"s",true,false,null],"b":{"c":{},"d":[]}}` {
+		t.Errorf("unexpected JSON %s", v.JSON())
+	}
+
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	raw, _ := jsonlite.Parse(`{"c": {}, "d": []}`)
+	v, err = jsonlite.ValueOf([]any{int8(-1), uint16(2), float32(0.1), json.Number("1.50"), raw, when, struct {
+		X int `json:"x"`
+	}{1}, map[string]int{"k": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want2 = `[-1,2,0.1,1.50,{"c": {}, "d": []},"2024-01-02T03:04:05Z",{"x":1},{"k":1}]`
+	if v.JSON() != want2 {
+		t.Errorf("expected %s, got %s", want2, v.JSON())
+	}
+	if v.Index(6).Lookup("x").Int() != 1 || jsonlite.As[time.Time](v.Index(5)) != when {
+		t.Errorf("unexpected values in %s", v.JSON())
+	}
+}
+
+func TestValueOfErrors(t *testing.T) {
+	tests := []struct {
+		value any
+		path  string
+	}{
+		{math.NaN(), `$`},
+		{map[string]any{"a": []any{1, float32(math.Inf(1))}}, `$.a[1]`},
+		{[]any{json.Number("x")}, `$[0]`},
+		{[]any{map[string]any{"ch": make(chan int)}}, `$[0].ch`},
+		{[]any{struct{ F []float64 }{[]float64{math.NaN()}}}, `$[0].F[0]`},
+	}
+	for _, tt := range tests {
+		_, err := jsonlite.ValueOf(tt.value)
+		var e *jsonlite.MarshalError
+		if !errors.As(err, &e) || !errors.Is(err, jsonlite.ErrUnsupportedValue) {
+			t.Fatalf("%v: expected marshal error, got %v", tt.value, err)
+		}
+		if e.Path != tt.path {
+			t.Errorf("%v: expected error at %s, got %s", tt.value, tt.path, e.Path)
+		}
+	}
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	var builder jsonlite.Builder
+	for b.Loop() {
+		builder.Object(func(o *jsonlite.ObjectBuilder) {
+			o.Array("items", func(a *jsonlite.ArrayBuilder) {
+				for i := range 100 {
+					a.Object(func(o *jsonlite.ObjectBuilder) {
+						o.Int("id", int64(i))
+						o.String("name", "some name")
+						o.Float("price", 9.99)
+					})
+				}
+			})
+			o.Int("total", 100)
+		})
+	}
+}
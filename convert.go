@@ -328,18 +328,7 @@ func asAny(v *Value) any {
 	case False:
 		return false
 	case Number:
-		switch v.NumberType() {
-		case Int:
-			return v.Int()
-		case Uint:
-			u := v.Uint()
-			if u <= math.MaxInt64 {
-				return int64(u)
-			}
-			return u
-		default:
-			return v.Float()
-		}
+		return anyNumber(v.json())
 	case String:
 		return v.String()
 	case Array:
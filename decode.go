@@ -0,0 +1,634 @@
+package jsonlite
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	numberType          = reflect.TypeFor[json.Number]()
+)
+
+// DecodeIterator decodes the current value of it into dst, which must be a
+// non-nil pointer, directly from the token stream and without building a tree
+// of Value nodes. If Next was not called yet, the iterator is first advanced
+// to the first value; io.EOF is returned if the input contains no value.
+//
+// Values are decoded following the rules of encoding/json: struct fields are
+// matched by the name in their json tag or by their Go name, exactly or else
+// case-insensitively, and the fields of embedded structs are promoted. Unknown
+// fields are skipped with Skip, which validates them. JSON null leaves values
+// unchanged, except pointers, interfaces, maps and slices which are set to nil.
+// Types implementing json.Unmarshaler receive the raw JSON of their value, and
+// types implementing encoding.TextUnmarshaler the content of JSON strings.
+// Values of empty interfaces are decoded to the representation of As[any].
+//
+// Strings without escape sequences are not copied: they reference the input
+// of the iterator, which must not be modified while they are in use.
+//
+// The decoder of each Go type is built once and cached. A *TypeError is
+// returned when a value does not match the type it is decoded into, and
+// decoding stops at the first error.
+func DecodeIterator(it *Iterator, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("jsonlite: DecodeIterator requires a non-nil pointer, got %T", dst)
+	}
+	if it.token == "" && it.err == nil {
+		if !it.Next() {
+			if it.err != nil {
+				return it.err
+			}
+			return io.EOF
+		}
+	}
+	if it.err != nil {
+		return it.err
+	}
+	return typeDecoderOf(v.Type().Elem())(it, v.Elem())
+}
+
+// typeDecoder decodes the current value of src into v, which is addressable.
+// The same decoders serve DecodeIterator, reading from the token stream of an
+// Iterator, and Unmarshal, walking a tree of parsed values.
+type typeDecoder func(src decodeSource, v reflect.Value) error
+
+// decodeSource is the input of the decoders: an Iterator, or a valueSource.
+type decodeSource interface {
+	// Kind returns the kind of the current value.
+	Kind() Kind
+	// number returns the text of the current value, which is a number.
+	number() string
+	// unquote returns the content of the current value, which is a string.
+	unquote() (string, error)
+	// rawJSON returns the JSON text of the current value.
+	rawJSON() (string, error)
+	// enter starts decoding the elements or fields of the current value,
+	// which is an array or an object, with nextElement or nextField.
+	enter()
+	nextElement(i int) (bool, error)
+	nextField(i int) (string, bool, error)
+	// Skip moves past the current value, which is not decoded.
+	Skip() error
+	// typeError returns the error for the current value, which cannot be
+	// decoded into a Go value of type t.
+	typeError(t reflect.Type, err error) error
+}
+
+var typeDecoders sync.Map // map[reflect.Type]typeDecoder
+
+func typeDecoderOf(t reflect.Type) typeDecoder {
+	if d, ok := typeDecoders.Load(t); ok {
+		return d.(typeDecoder)
+	}
+	d, _ := typeDecoders.LoadOrStore(t, newTypeDecoder(t, map[reflect.Type]*typeDecoder{}))
+	return d.(typeDecoder)
+}
+
+// newTypeDecoder builds the decoder of type t. The decoders being built
+// are recorded in seen, so recursive types refer to them indirectly.
+func newTypeDecoder(t reflect.Type, seen map[reflect.Type]*typeDecoder) typeDecoder {
+	if d, ok := seen[t]; ok {
+		return func(src decodeSource, v reflect.Value) error { return (*d)(src, v) }
+	}
+	if d, ok := typeDecoders.Load(t); ok {
+		return d.(typeDecoder)
+	}
+	d := new(typeDecoder)
+	seen[t] = d
+
+	switch {
+	case t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(jsonUnmarshalerType):
+		*d = decodeJSONUnmarshaler
+	case t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textUnmarshalerType):
+		*d = decodeTextUnmarshaler
+	case t == numberType:
+		*d = decodeNumber
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			*d = decodeBool
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			*d = decodeNumeric
+		case reflect.String:
+			*d = decodeString
+		case reflect.Interface:
+			*d = decodeInterface
+		case reflect.Pointer:
+			*d = newPointerDecoder(newTypeDecoder(t.Elem(), seen))
+		case reflect.Slice:
+			if t.Elem().Kind() == reflect.Uint8 && !reflect.PointerTo(t.Elem()).Implements(jsonUnmarshalerType) {
+				*d = decodeBytes
+			} else {
+				*d = newSliceDecoder(newTypeDecoder(t.Elem(), seen))
+			}
+		case reflect.Array:
+			*d = newArrayDecoder(newTypeDecoder(t.Elem(), seen))
+		case reflect.Map:
+			*d = newMapDecoder(t, newTypeDecoder(t.Elem(), seen))
+		case reflect.Struct:
+			*d = newStructDecoder(t, seen)
+		default:
+			*d = decodeUnsupported
+		}
+	}
+	return *d
+}
+
+func decodeUnsupported(src decodeSource, v reflect.Value) error {
+	return src.typeError(v.Type(), fmt.Errorf("unsupported type"))
+}
+
+func decodeJSONUnmarshaler(src decodeSource, v reflect.Value) error {
+	raw, err := src.rawJSON()
+	if err != nil {
+		return err
+	}
+	return v.Addr().Interface().(json.Unmarshaler).UnmarshalJSON([]byte(raw))
+}
+
+func decodeTextUnmarshaler(src decodeSource, v reflect.Value) error {
+	switch src.Kind() {
+	case Null:
+		return nil
+	case String:
+		s, err := src.unquote()
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	default:
+		return src.typeError(v.Type(), nil)
+	}
+}
+
+func decodeNumber(src decodeSource, v reflect.Value) error {
+	switch src.Kind() {
+	case Null:
+		return nil
+	case Number:
+		v.SetString(src.number())
+		return nil
+	default:
+		return src.typeError(v.Type(), nil)
+	}
+}
+
+func decodeBool(src decodeSource, v reflect.Value) error {
+	switch src.Kind() {
+	case Null:
+		return nil
+	case True, False:
+		v.SetBool(src.Kind() == True)
+		return nil
+	default:
+		return src.typeError(v.Type(), nil)
+	}
+}
+
+func decodeNumeric(src decodeSource, v reflect.Value) error {
+	switch src.Kind() {
+	case Null:
+		return nil
+	case Number:
+		if err := setNumber(v, src.number()); err != nil {
+			return src.typeError(v.Type(), err)
+		}
+		return nil
+	default:
+		return src.typeError(v.Type(), nil)
+	}
+}
+
+// setNumber sets v, which is an integer or a floating-point number, from the
+// JSON number s. Returns an error if s does not fit in the type of v.
+func setNumber(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	}
+	return nil
+}
+
+func decodeString(src decodeSource, v reflect.Value) error {
+	switch src.Kind() {
+	case Null:
+		return nil
+	case String:
+		s, err := src.unquote()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+		return nil
+	default:
+		return src.typeError(v.Type(), nil)
+	}
+}
+
+func decodeBytes(src decodeSource, v reflect.Value) error {
+	switch src.Kind() {
+	case Null:
+		v.SetZero()
+		return nil
+	case String:
+		s, err := src.unquote()
+		if err != nil {
+			return err
+		}
+		b, err := decodeBase64(s)
+		if err != nil {
+			return src.typeError(v.Type(), err)
+		}
+		v.SetBytes(b)
+		return nil
+	default:
+		return src.typeError(v.Type(), nil)
+	}
+}
+
+// decodeBase64 decodes the content of a JSON string holding a []byte, which is
+// encoded in standard base64 like AppendBytes does.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.AppendDecode(make([]byte, 0, base64.StdEncoding.DecodedLen(len(s))), []byte(s))
+}
+
+func decodeInterface(src decodeSource, v reflect.Value) error {
+	if src.Kind() == Null {
+		v.SetZero()
+		return nil
+	}
+	if v.NumMethod() != 0 {
+		return src.typeError(v.Type(), nil)
+	}
+	x, err := decodeAny(src)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(&x).Elem())
+	return nil
+}
+
+func newPointerDecoder(elem typeDecoder) typeDecoder {
+	return func(src decodeSource, v reflect.Value) error {
+		if src.Kind() == Null {
+			v.SetZero()
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return elem(src, v.Elem())
+	}
+}
+
+func newSliceDecoder(elem typeDecoder) typeDecoder {
+	return func(src decodeSource, v reflect.Value) error {
+		switch src.Kind() {
+		case Null:
+			v.SetZero()
+			return nil
+		case Array:
+		default:
+			return src.typeError(v.Type(), nil)
+		}
+		// Like encoding/json, the elements of the slice are reused: they are
+		// decoded in place, and the slice is truncated to the length of the
+		// JSON array.
+		src.enter()
+		for n := 0; ; n++ {
+			ok, err := src.nextElement(n)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				if v.IsNil() {
+					v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+				}
+				v.SetLen(n)
+				return nil
+			}
+			if n == v.Cap() {
+				v.Grow(1)
+			}
+			if n >= v.Len() {
+				v.SetLen(n + 1)
+			}
+			if err := elem(src, v.Index(n)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func newArrayDecoder(elem typeDecoder) typeDecoder {
+	return func(src decodeSource, v reflect.Value) error {
+		switch src.Kind() {
+		case Null:
+			return nil
+		case Array:
+		default:
+			return src.typeError(v.Type(), nil)
+		}
+		// Elements past the length of the Go array are skipped, and the Go
+		// elements past the length of the JSON array are zeroed.
+		src.enter()
+		for i := 0; ; i++ {
+			ok, err := src.nextElement(i)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				for ; i < v.Len(); i++ {
+					v.Index(i).SetZero()
+				}
+				return nil
+			}
+			if i < v.Len() {
+				if err := elem(src, v.Index(i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func newMapDecoder(t reflect.Type, elem typeDecoder) typeDecoder {
+	keyType := t.Key()
+	switch keyType.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		return decodeUnsupported
+	}
+
+	return func(src decodeSource, v reflect.Value) error {
+		switch src.Kind() {
+		case Null:
+			v.SetZero()
+			return nil
+		case Object:
+		default:
+			return src.typeError(v.Type(), nil)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		key := reflect.New(keyType).Elem()
+		value := reflect.New(t.Elem()).Elem()
+		src.enter()
+		for i := 0; ; i++ {
+			k, ok, err := src.nextField(i)
+			if err != nil || !ok {
+				return err
+			}
+			if err := setMapKey(key, k); err != nil {
+				return src.typeError(keyType, err)
+			}
+			value.SetZero()
+			if err := elem(src, value); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	}
+}
+
+// setMapKey sets the map key v, which is a string or an integer, from the
+// JSON object key k.
+func setMapKey(v reflect.Value, k string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(k)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(k, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	default:
+		n, err := strconv.ParseUint(k, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	}
+	return nil
+}
+
+func newStructDecoder(t reflect.Type, seen map[reflect.Type]*typeDecoder) typeDecoder {
+	fields := typeFields(t)
+	decoders := make([]typeDecoder, len(fields.list))
+	for i, f := range fields.list {
+		if f.quoted {
+			decoders[i] = newQuotedDecoder(f.typ)
+		} else {
+			decoders[i] = newTypeDecoder(f.typ, seen)
+		}
+	}
+
+	return func(src decodeSource, v reflect.Value) error {
+		switch src.Kind() {
+		case Null:
+			return nil
+		case Object:
+		default:
+			return src.typeError(v.Type(), nil)
+		}
+		src.enter()
+		for n := 0; ; n++ {
+			k, ok, err := src.nextField(n)
+			if err != nil || !ok {
+				return err
+			}
+			i := fields.lookup(k)
+			if i < 0 {
+				if err := src.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			f, ok := fieldByIndex(v, fields.list[i].index)
+			if !ok {
+				return src.typeError(fields.list[i].typ, fmt.Errorf("cannot set embedded pointer to unexported struct"))
+			}
+			if err := decoders[i](src, f); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// newQuotedDecoder returns the decoder of struct fields of type t with
+// the ",string" option, whose values are encoded in JSON strings. The content
+// of the strings is parsed and decoded by unmarshalQuoted.
+func newQuotedDecoder(t reflect.Type) typeDecoder {
+	return func(src decodeSource, v reflect.Value) error {
+		switch src.Kind() {
+		case Null:
+			return typeDecoderOf(t)(src, v)
+		case String:
+			s, err := src.unquote()
+			if err != nil {
+				return err
+			}
+			if err := unmarshalQuoted(s, v); err != nil {
+				return src.typeError(t, err)
+			}
+			return nil
+		default:
+			return src.typeError(t, nil)
+		}
+	}
+}
+
+// unmarshalQuoted decodes s, the content of the JSON string holding the value
+// of a struct field with the ",string" option, into dst. The content must be
+// a JSON string for string fields, and a literal or a number otherwise.
+func unmarshalQuoted(s string, dst reflect.Value) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	if dst.Kind() == reflect.Pointer {
+		if v.Kind() == Null {
+			dst.SetZero()
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		dst = dst.Elem()
+	}
+	switch k := v.Kind(); {
+	case k == Null:
+	case k == String && dst.Kind() == reflect.String:
+		dst.SetString(v.String())
+	case (k == True || k == False) && dst.Kind() == reflect.Bool:
+		dst.SetBool(k == True)
+	case k == Number && dst.Kind() != reflect.String && dst.Kind() != reflect.Bool:
+		return setNumber(dst, v.json())
+	default:
+		return fmt.Errorf("invalid use of ,string struct tag, trying to decode %q into %v", s, dst.Type())
+	}
+	return nil
+}
+
+// unquote returns the content of the current value, which is a string. The
+// string is marked as consumed, since unquoting validates it.
+func (it *Iterator) unquote() (string, error) {
+	s, err := Unquote(it.token)
+	if err != nil {
+		it.setError(tokenError(it.token, it.tokens.json))
+		return "", it.err
+	}
+	it.consumed = true
+	return s, nil
+}
+
+func (it *Iterator) number() string { return it.token }
+
+func (it *Iterator) rawJSON() (string, error) {
+	raw := it.Raw()
+	return raw, it.err
+}
+
+func (it *Iterator) enter() { it.consumed = true }
+
+func (it *Iterator) typeError(t reflect.Type, err error) error {
+	return &TypeError{Path: it.jsonPath(), Offset: int(it.Offset()), Kind: it.kind, Type: t, Err: err}
+}
+
+// decodeAny decodes the current value of src with the representation of
+// As[any].
+func decodeAny(src decodeSource) (any, error) {
+	switch src.Kind() {
+	case Null:
+		return nil, nil
+	case True:
+		return true, nil
+	case False:
+		return false, nil
+	case Number:
+		return anyNumber(src.number()), nil
+	case String:
+		return src.unquote()
+	case Array:
+		src.enter()
+		a := []any{}
+		for i := 0; ; i++ {
+			ok, err := src.nextElement(i)
+			if err != nil || !ok {
+				return a, err
+			}
+			x, err := decodeAny(src)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, x)
+		}
+	default:
+		src.enter()
+		m := map[string]any{}
+		for i := 0; ; i++ {
+			k, ok, err := src.nextField(i)
+			if err != nil || !ok {
+				return m, err
+			}
+			x, err := decodeAny(src)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = x
+		}
+	}
+}
+
+// anyNumber returns the representation of the JSON number s in As[any]: an
+// int64 when it is an integer which fits, a uint64 for larger integers, and a
+// float64 otherwise.
+func anyNumber(s string) any {
+	switch NumberTypeOf(s) {
+	case Int:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case Uint:
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+			if n <= math.MaxInt64 {
+				return int64(n)
+			}
+			return n
+		}
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
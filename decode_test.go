@@ -0,0 +1,331 @@
+package jsonlite_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+type decodeBase struct {
+	ID      int64  `json:"id"`
+	Created string `json:"created"`
+}
+
+type DecodeExtra struct {
+	Note string
+}
+
+type decodeUpper string
+
+func (u *decodeUpper) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*u = decodeUpper(strings.ToUpper(s))
+	return nil
+}
+
+type decodeRecord struct {
+	decodeBase
+	*DecodeExtra
+	Name     string             `json:"name"`
+	Tags     []string           `json:"tags,omitempty"`
+	Scores   map[string]float64 `json:"scores"`
+	ByID     map[int]bool       `json:"by_id"`
+	Parent   *decodeRecord      `json:"parent"`
+	Children []decodeRecord     `json:"children"`
+	Any      any                `json:"any"`
+	Number   json.Number        `json:"number"`
+	Data     []byte             `json:"data"`
+	Pair     [2]int             `json:"pair"`
+	When     time.Time          `json:"when"`
+	Upper    decodeUpper        `json:"upper"`
+	Ignored  string             `json:"-"`
+	Small    int8               `json:"small"`
+	Ratio    float32            `json:"ratio"`
+	Count    uint16             `json:"count"`
+	Enabled  bool               `json:"enabled"`
+	Nullable *int               `json:"nullable"`
+	private  int
+}
+
+const decodeInput = `{
+	"id": 42,
+	"created": "2024-01-02",
+	"Note": "embedded pointer",
+	"NAME": "case insensitive",
+	"tags": ["a", "b\n", "é"],
+	"scores": {"x": 1.5, "y": -2},
+	"by_id": {"1": true, "20": false},
+	"parent": {"id": 1, "name": "root", "children": []},
+	"children": [{"name": "c1", "pair": [1]}, {"name": "c2", "any": null}],
+	"any": {"list": [1, -2, 3.5, "s", true, null, 18446744073709551615], "obj": {}},
+	"number": 12.50,
+	"data": "aGVsbG8=",
+	"pair": [7, 8, 9],
+	"when": "2024-05-06T07:08:09Z",
+	"upper": "shout",
+	"Ignored": "nope",
+	"small": -128,
+	"ratio": 0.25,
+	"count": 65535,
+	"enabled": true,
+	"nullable": null,
+	"private": 1,
+	"unknown": {"deep": [1, {"x": [true]}]}
+}`
+
+func TestDecodeIterator(t *testing.T) {
+	var want decodeRecord
+	if err := json.Unmarshal([]byte(decodeInput), &want); err != nil {
+		t.Fatal(err)
+	}
+	var got decodeRecord
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(decodeInput), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	// encoding/json decodes numbers in interfaces as float64.
+	if n := got.Any.(map[string]any)["list"].([]any)[0]; n != int64(1) {
+		t.Errorf("expected int64(1), got %T(%v)", n, n)
+	}
+	got.Any, want.Any = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", want, got)
+	}
+	if got.Note != "embedded pointer" || got.Upper != "SHOUT" || string(got.Data) != "hello" || got.Pair != [2]int{7, 8} {
+		t.Errorf("unexpected values %+v", got)
+	}
+}
+
+func TestDecodeIteratorNull(t *testing.T) {
+	n := 1
+	v := decodeRecord{
+		Name:     "kept",
+		Tags:     []string{"x"},
+		Scores:   map[string]float64{"x": 1},
+		Parent:   &decodeRecord{},
+		Any:      1,
+		Nullable: &n,
+	}
+	input := `{"name": null, "tags": null, "scores": null, "parent": null, "any": null, "nullable": null, "small": null}`
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(input), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "kept" || v.Tags != nil || v.Scores != nil || v.Parent != nil || v.Any != nil || v.Nullable != nil {
+		t.Errorf("unexpected values after decoding nulls: %+v", v)
+	}
+}
+
+func TestDecodeIteratorReuse(t *testing.T) {
+	// Slices are truncated and maps are merged, like encoding/json.
+	v := struct {
+		List []int
+		Map  map[string]int
+	}{
+		List: []int{1, 2, 3},
+		Map:  map[string]int{"a": 1},
+	}
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(`{"List": [4], "Map": {"b": 2}}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v.List, []int{4}) || !reflect.DeepEqual(v.Map, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("unexpected values %+v", v)
+	}
+
+	var empty []int
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(`[]`), &empty); err != nil {
+		t.Fatal(err)
+	}
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("expected empty slice, got %#v", empty)
+	}
+}
+
+func TestDecodeIteratorSequence(t *testing.T) {
+	type item struct {
+		N int `json:"n"`
+	}
+	it := jsonlite.IterateReader(strings.NewReader(`{"n": 1} {"n": 2, "x": [1, 2]} {"n": 3}`))
+	var got []int
+	for {
+		var v item
+		if err := jsonlite.DecodeIterator(it, &v); err != nil {
+			if err != io.EOF {
+				t.Fatal(err)
+			}
+			break
+		}
+		got = append(got, v.N)
+		if !it.Next() {
+			break
+		}
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", got)
+	}
+
+	var v item
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(`  `), &v); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecodeIteratorField(t *testing.T) {
+	// A value can be decoded from within a loop over an object.
+	it := jsonlite.Iterate(`{"meta": {"v": 1}, "items": [{"a": 1}, {"a": 2}], "tail": true}`)
+	it.Next()
+	var items []map[string]int
+	var keys []string
+	for key, err := range it.Object {
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		if key == "items" {
+			if err := jsonlite.DecodeIterator(it, &items); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if !reflect.DeepEqual(keys, []string{"meta", "items", "tail"}) || len(items) != 2 || items[1]["a"] != 2 {
+		t.Errorf("unexpected result %v %v", keys, items)
+	}
+}
+
+func TestDecodeIteratorZeroCopy(t *testing.T) {
+	input := `{"name": "plain", "escaped": "a\"b"}`
+	var v struct {
+		Name    string `json:"name"`
+		Escaped string `json:"escaped"`
+	}
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(input), &v); err != nil {
+		t.Fatal(err)
+	}
+	start := uintptr(unsafe.Pointer(unsafe.StringData(input)))
+	if p := uintptr(unsafe.Pointer(unsafe.StringData(v.Name))); p < start || p >= start+uintptr(len(input)) {
+		t.Error("expected string without escapes to reference the input")
+	}
+	if v.Escaped != `a"b` {
+		t.Errorf("expected unescaped string, got %q", v.Escaped)
+	}
+}
+
+func TestDecodeIteratorErrors(t *testing.T) {
+	type inner struct {
+		Values []int8 `json:"values"`
+	}
+	type outer struct {
+		Items []inner        `json:"items"`
+		Name  string         `json:"name"`
+		Iface fmtStringer    `json:"iface"`
+		Map   map[string]int `json:"map"`
+	}
+
+	tests := []struct {
+		input  string
+		err    error
+		path   string
+		offset int
+	}{
+		{`{"items": [{"values": [1, 200]}]}`, jsonlite.ErrTypeMismatch, `$.items[0].values[1]`, 26},
+		{`{"items": [{}, {"values": "x"}]}`, jsonlite.ErrTypeMismatch, `$.items[1].values`, 26},
+		{`{"name": 1}`, jsonlite.ErrTypeMismatch, `$.name`, 9},
+		{`{"map": {"a": true}}`, jsonlite.ErrTypeMismatch, `$.map.a`, 14},
+		{`{"iface": "x"}`, jsonlite.ErrTypeMismatch, `$.iface`, 10},
+		{`[]`, jsonlite.ErrTypeMismatch, `$`, 0},
+		{`{"items": [1.5]}`, jsonlite.ErrTypeMismatch, `$.items[0]`, 11},
+		{`{"unknown": [1, tru], "name": "x"}`, jsonlite.ErrUnexpectedToken, ``, 0},
+		{`{"name": "\x"}`, jsonlite.ErrInvalidEscape, ``, 0},
+		{`{"items": [{"values": [1,]}]}`, jsonlite.ErrUnexpectedToken, ``, 0},
+		{`{"items": [`, jsonlite.ErrTruncated, ``, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var v outer
+			err := jsonlite.DecodeIterator(jsonlite.Iterate(tt.input), &v)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if tt.path == "" {
+				return
+			}
+			var e *jsonlite.TypeError
+			if !errors.As(err, &e) {
+				t.Fatalf("expected type error, got %T", err)
+			}
+			if e.Path != tt.path || e.Offset != tt.offset {
+				t.Errorf("expected error at %s (offset %d), got %s (offset %d)", tt.path, tt.offset, e.Path, e.Offset)
+			}
+		})
+	}
+
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(`1`), outer{}); err == nil {
+		t.Error("expected error decoding into a non-pointer")
+	}
+}
+
+type fmtStringer interface{ String() string }
+
+func TestDecodeIteratorRecursive(t *testing.T) {
+	type node struct {
+		Value    int     `json:"value"`
+		Children []*node `json:"children"`
+	}
+	var root node
+	input := `{"value": 1, "children": [{"value": 2, "children": [{"value": 3}]}, {"value": 4}]}`
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(input), &root); err != nil {
+		t.Fatal(err)
+	}
+	if root.Children[0].Children[0].Value != 3 || root.Children[1].Value != 4 {
+		t.Errorf("unexpected tree %+v", root)
+	}
+}
+
+func BenchmarkDecodeIterator(b *testing.B) {
+	type item struct {
+		ID    int64    `json:"id"`
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Price float64  `json:"price"`
+	}
+	type payload struct {
+		Items []item `json:"items"`
+		Total int    `json:"total"`
+	}
+	input := `{"items": [` + strings.Repeat(`{"id": 12345, "name": "some name", "tags": ["a", "b"], "price": 9.99, "extra": {"x": 1}}, `, 99) +
+		`{"id": 1, "name": "last", "tags": [], "price": 0}], "total": 100}`
+	b.SetBytes(int64(len(input)))
+
+	b.Run("DecodeIterator", func(b *testing.B) {
+		it := jsonlite.Iterate(input)
+		var v payload
+		for b.Loop() {
+			it.Reset(input)
+			if err := jsonlite.DecodeIterator(it, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("encoding/json", func(b *testing.B) {
+		data := []byte(input)
+		var v payload
+		for b.Loop() {
+			if err := json.Unmarshal(data, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
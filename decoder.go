@@ -0,0 +1,282 @@
+package jsonlite
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"unsafe"
+)
+
+const (
+	// defaultDecoderBufferSize is the initial size of the Decoder buffer.
+	defaultDecoderBufferSize = 4096
+	// recordSeparator is the ASCII RS character which starts each record of
+	// a JSON text sequence (RFC 7464).
+	recordSeparator = 0x1E
+)
+
+// Decoder reads a stream of JSON values from an io.Reader.
+//
+// The values may be separated by newlines (JSON Lines), by any whitespace
+// (concatenated JSON), or framed with record separators as defined by
+// RFC 7464 (JSON text sequences).
+//
+// The decoder only buffers the data needed to parse the next value, so memory
+// usage is bounded by the size of the largest value rather than the size of
+// the stream.
+type Decoder struct {
+	r      io.Reader
+	parser parser
+	buf    []byte
+	pos    int // offset of the first byte of buf not consumed yet
+	err    error
+	eof    bool
+	rerr   error // error returned by the reader, other than io.EOF
+	// detached is set when values returned by Decode still reference buf,
+	// in which case the buffer is not reused when compacted.
+	detached bool
+	scanner  valueScanner
+	// Position of buf[0] in the stream, used to locate syntax errors.
+	position position
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{r: r}
+	d.parser.configure(&ParseOptions{})
+	return d
+}
+
+// Decode reads the next JSON value from the stream.
+//
+// The returned value references the internal buffer of the decoder, it
+// remains valid until the next call to Decode, unless Detach is called.
+//
+// Returns io.EOF when there are no more values in the stream. Syntax errors
+// are reported as *SyntaxError with positions relative to the beginning of
+// the stream, after which all calls to Decode return the same error.
+func (d *Decoder) Decode() (*Value, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	for {
+		d.skip()
+		if d.pos == len(d.buf) {
+			if d.eof {
+				d.err = io.EOF
+				if d.rerr != nil {
+					d.err = d.rerr
+				}
+				return nil, d.err
+			}
+			d.fill()
+			continue
+		}
+
+		if !d.scanner.scanValue(d.buf[d.pos:], d.parser.maxNesting) && !d.eof {
+			d.fill()
+			continue
+		}
+
+		s := unsafe.String(&d.buf[d.pos], len(d.buf)-d.pos)
+		v, rest, err := d.parser.parseValue(s)
+		if err != nil {
+			if d.rerr != nil && errors.Is(err, ErrTruncated) {
+				err = d.rerr
+			}
+			d.err = d.locate(err)
+			return nil, d.err
+		}
+		d.pos = len(d.buf) - len(rest)
+		return &v, nil
+	}
+}
+
+// All returns an iterator over the values of the stream.
+//
+// Each value is valid until the next iteration, unless Detach is called.
+// The iteration stops after yielding the first error.
+func (d *Decoder) All() iter.Seq2[*Value, error] {
+	return func(yield func(*Value, error) bool) {
+		for {
+			v, err := d.Decode()
+			if err == io.EOF {
+				return
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Detach makes the values returned by Decode so far remain valid after
+// subsequent calls to Decode. The decoder stops reusing the memory they
+// reference and allocates a new buffer when it needs to read more data.
+func (d *Decoder) Detach() { d.detached = true }
+
+// InputOffset returns the offset in the stream of the current position of the
+// decoder, which is the end of the last value returned by Decode.
+func (d *Decoder) InputOffset() int64 { return int64(d.position.offset + d.pos) }
+
+// valueScanner finds the end of JSON values in data received incrementally.
+// The state of the scan is kept when the end of a value is not found, and
+// resumed when more data is available, so the input is scanned only once.
+type valueScanner struct {
+	scan   int    // offset relative to the value up to which it was scanned
+	stack  []byte // closing brackets expected by the value
+	quoted bool   // whether the scan stopped inside a string
+}
+
+// scanValue scans b, which starts with a value, and reports whether it
+// contains the complete value. The scan stops early at mismatched brackets,
+// leaving the parser to report the error, or when the value is nested deeper
+// than maxNesting.
+func (s *valueScanner) scanValue(b []byte, maxNesting int) bool {
+	i := s.scan
+
+	switch b[0] {
+	case '"', '[', '{':
+	default:
+		// Numbers and literals are terminated by a delimiter, which may
+		// only be found after reading more data.
+		for i < len(b) && !isDelimiter(b[i]) {
+			i++
+		}
+		s.scan = i
+		return s.scanned(i < len(b))
+	}
+
+	for i < len(b) {
+		if s.quoted {
+			k := bytes.IndexByte(b[i:], '"')
+			if k < 0 {
+				i = len(b)
+				break
+			}
+			i += k
+			// Count preceding backslashes to check if quote is escaped,
+			// the opening quote guarantees that the loop terminates.
+			n := 0
+			for j := i - 1; b[j] == '\\'; j-- {
+				n++
+			}
+			i++
+			if n%2 == 0 {
+				s.quoted = false
+				if len(s.stack) == 0 {
+					return s.scanned(true)
+				}
+			}
+			continue
+		}
+		switch c := b[i]; c {
+		case '"':
+			s.quoted = true
+		case '[', '{':
+			if len(s.stack) == maxNesting {
+				// The parser rejects the value without reading further.
+				return s.scanned(true)
+			}
+			if c == '[' {
+				s.stack = append(s.stack, ']')
+			} else {
+				s.stack = append(s.stack, '}')
+			}
+		case ']', '}':
+			if len(s.stack) == 0 || s.stack[len(s.stack)-1] != c {
+				return s.scanned(true)
+			}
+			if s.stack = s.stack[:len(s.stack)-1]; len(s.stack) == 0 {
+				return s.scanned(true)
+			}
+		}
+		i++
+	}
+
+	s.scan = i
+	return false
+}
+
+// scanned resets the scan state once the end of a value was found.
+func (s *valueScanner) scanned(done bool) bool {
+	if done {
+		s.scan, s.stack, s.quoted = 0, s.stack[:0], false
+	}
+	return done
+}
+
+// skip advances past the whitespace and record separators at the current
+// position of the buffer.
+func (d *Decoder) skip() {
+	for d.pos < len(d.buf) {
+		if c := d.buf[d.pos]; !isWhitespace(c) && c != recordSeparator {
+			break
+		}
+		d.pos++
+	}
+}
+
+// fill discards the consumed part of the buffer and reads more data from the
+// underlying reader, growing the buffer if it is full.
+func (d *Decoder) fill() {
+	d.position.advance(d.buf[:d.pos])
+
+	buf, remain := d.buf, len(d.buf)-d.pos
+	switch {
+	case remain == cap(d.buf):
+		buf = make([]byte, remain, max(2*cap(d.buf), defaultDecoderBufferSize))
+	case d.detached:
+		buf = make([]byte, remain, cap(d.buf))
+	}
+	d.buf = buf[:copy(buf[:remain], d.buf[d.pos:])]
+	d.pos = 0
+	d.detached = false
+
+	n, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+	d.buf = d.buf[:len(d.buf)+n]
+	if err != nil {
+		d.eof = true
+		if err != io.EOF {
+			d.rerr = err
+		}
+	}
+}
+
+// locate sets the position of err relative to the beginning of the stream.
+func (d *Decoder) locate(err error) error {
+	return d.position.locate(err, d.buf, d.pos)
+}
+
+// position is the position of the beginning of a buffer in a stream, used
+// to locate syntax errors in input which is read incrementally.
+type position struct {
+	offset int
+	line   int
+	column int
+}
+
+// locate sets the position of err relative to the beginning of the stream.
+// The buf argument holds the input following the position, in which the value
+// containing the error starts at offset start.
+func (p *position) locate(err error, buf []byte, start int) error {
+	e, ok := err.(*SyntaxError)
+	if !ok {
+		return err
+	}
+	e.locate(unsafe.String(unsafe.SliceData(buf), len(buf)), start)
+	e.shift(p.offset, p.line, p.column)
+	return e
+}
+
+// advance moves the position past the consumed bytes.
+func (p *position) advance(consumed []byte) {
+	p.offset += len(consumed)
+	if n := bytes.Count(consumed, []byte{'\n'}); n > 0 {
+		p.line += n
+		p.column = len(consumed) - (bytes.LastIndexByte(consumed, '\n') + 1)
+	} else {
+		p.column += len(consumed)
+	}
+}
@@ -0,0 +1,172 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"whitespace", " \n\t ", nil},
+		{"json lines", "{\"a\":1}\n{\"b\":2}\n{\"c\":3}\n", []string{`{"a":1}`, `{"b":2}`, `{"c":3}`}},
+		{"no trailing newline", "1\n2\n3", []string{"1", "2", "3"}},
+		{"concatenated", `{"a":1}{"b":2} [3]"x"true null -1.5`, []string{`{"a":1}`, `{"b":2}`, `[3]`, `"x"`, `true`, `null`, `-1.5`}},
+		{"json text sequence", "\x1e{\"a\":1}\n\x1e[1,2]\n\x1e42\n", []string{`{"a":1}`, `[1,2]`, `42`}},
+		{"nested brackets in strings", `{"a":"]}"}["[{\"",{}]`, []string{`{"a":"]}"}`, `["[{\"",{}]`}},
+	}
+
+	for _, tt := range tests {
+		for _, reader := range []struct {
+			name string
+			wrap func(io.Reader) io.Reader
+		}{
+			{"full", func(r io.Reader) io.Reader { return r }},
+			{"one byte", iotest.OneByteReader},
+			{"half", iotest.HalfReader},
+		} {
+			t.Run(tt.name+"/"+reader.name, func(t *testing.T) {
+				dec := jsonlite.NewDecoder(reader.wrap(strings.NewReader(tt.input)))
+				var got []string
+				for v, err := range dec.All() {
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, strings.Clone(v.JSON()))
+				}
+				if len(got) != len(tt.want) {
+					t.Fatalf("expected %d values, got %d: %q", len(tt.want), len(got), got)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("value %d: expected %s, got %s", i, tt.want[i], got[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestDecoderLargeValues(t *testing.T) {
+	var input strings.Builder
+	for i := range 100 {
+		input.WriteString(`{"id":`)
+		input.WriteString(strings.Repeat("1", i+1))
+		input.WriteString(`,"data":"`)
+		input.WriteString(strings.Repeat("x", i*100))
+		input.WriteString("\"}\n")
+	}
+
+	dec := jsonlite.NewDecoder(iotest.HalfReader(strings.NewReader(input.String())))
+	n := 0
+	for v, err := range dec.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(v.Lookup("data").String()); got != n*100 {
+			t.Fatalf("value %d: expected %d bytes of data, got %d", n, n*100, got)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Errorf("expected 100 values, got %d", n)
+	}
+	if off := dec.InputOffset(); off != int64(input.Len()) {
+		t.Errorf("expected input offset %d, got %d", input.Len(), off)
+	}
+}
+
+func TestDecoderDetach(t *testing.T) {
+	var input strings.Builder
+	for i := range 1000 {
+		fmt.Fprintf(&input, "{\"key\":%d}\n", i)
+	}
+	dec := jsonlite.NewDecoder(strings.NewReader(input.String()))
+
+	var values []*jsonlite.Value
+	for v, err := range dec.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec.Detach()
+		values = append(values, v)
+	}
+	if len(values) != 1000 {
+		t.Fatalf("expected 1000 values, got %d", len(values))
+	}
+	for i, v := range values {
+		if got, want := v.JSON(), fmt.Sprintf(`{"key":%d}`, i); got != want {
+			t.Fatalf("value %d was overwritten: expected %s, got %s", i, want, got)
+		}
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+	input := strings.Repeat(`{"a":1}`+"\n", 1000) + `{"a":[1,2,x]}` + "\n" + `{"a":1}`
+	dec := jsonlite.NewDecoder(iotest.HalfReader(strings.NewReader(input)))
+
+	n := 0
+	var err error
+	for _, err = range dec.All() {
+		if err != nil {
+			break
+		}
+		n++
+	}
+	if n != 1000 {
+		t.Errorf("expected 1000 values before the error, got %d", n)
+	}
+	checkSyntaxError(t, err, 8010, 1001, 11, "x", "$.a[2]")
+
+	if _, again := dec.Decode(); again != err {
+		t.Errorf("expected the error to be sticky, got %v", again)
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	dec := jsonlite.NewDecoder(strings.NewReader("{\"a\":1}\n{\"a\":[1,"))
+	if _, err := dec.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dec.Decode(); !errors.Is(err, jsonlite.ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+}
+
+func TestDecoderReadError(t *testing.T) {
+	errRead := errors.New("read error")
+	r := io.MultiReader(strings.NewReader(`{"a":1} {"a":`), iotest.ErrReader(errRead))
+	dec := jsonlite.NewDecoder(r)
+	if _, err := dec.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dec.Decode(); !errors.Is(err, errRead) {
+		t.Errorf("expected read error, got %v", err)
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	input := strings.Repeat(cloudLoggingPayload+"\n", 100)
+	r := strings.NewReader(input)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	for b.Loop() {
+		r.Reset(input)
+		dec := jsonlite.NewDecoder(r)
+		for _, err := range dec.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package jsonlite
+
+import (
+	"fmt"
+	"hash/maphash"
+	"strconv"
+	"unsafe"
+)
+
+// With returns a copy of v where the value at the JSON Pointer ptr is replaced
+// by x, or added if ptr refers to a missing key of an object. A nil x is
+// treated as JSON null, and an empty ptr replaces the whole document.
+//
+// Values are never modified: v and the values obtained from it keep their
+// content, and can still be used concurrently with the returned value. The
+// arrays and objects containing the edited value are copied, and their JSON
+// text and key index rebuilt, while all the other values are shared with v.
+// The same applies to Without, InsertAt and AppendAt.
+//
+// Returns an error wrapping ErrInvalidPointer if ptr is invalid, or
+// ErrPointerNotFound if it does not match the shape of the document.
+func (v *Value) With(ptr string, x *Value) (*Value, error) {
+	tokens, err := pointerTokens(ptr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		root := valueOrNull(x)
+		return &root, nil
+	}
+	last := len(tokens) - 1
+	key := tokens[last]
+	return v.edit(ptr, tokens[:last], func(parent *Value) (Value, error) {
+		if parent.Kind() == Object {
+			fields := parent.fields()
+			for i := range fields {
+				if fields[i].k == key {
+					return spliceObject(parent, i, i+1, field{k: key, v: valueOrNull(x)}), nil
+				}
+			}
+			return spliceObject(parent, len(fields), len(fields), field{k: key, v: valueOrNull(x)}), nil
+		}
+		if _, err := resolvePointerToken(parent, key); err != nil {
+			return Value{}, &PointerError{Pointer: ptr, Token: last, Err: err}
+		}
+		i, _ := pointerIndex(key)
+		return spliceArray(parent, i, i+1, valueOrNull(x)), nil
+	})
+}
+
+// Without returns a copy of v where the value at the JSON Pointer ptr is
+// removed from the array or object containing it. If the object has several
+// fields with the key, the first one is removed, like Lookup returns it.
+//
+// Returns an error wrapping ErrInvalidPointer if ptr is invalid or empty, or
+// ErrPointerNotFound if it does not refer to a value in the document.
+func (v *Value) Without(ptr string) (*Value, error) {
+	tokens, err := pointerTokens(ptr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, &PointerError{Pointer: ptr, Token: -1, Err: fmt.Errorf("%w: cannot remove the root value", ErrInvalidPointer)}
+	}
+	last := len(tokens) - 1
+	return v.edit(ptr, tokens[:last], func(parent *Value) (Value, error) {
+		child, err := resolvePointerToken(parent, tokens[last])
+		if err != nil {
+			return Value{}, &PointerError{Pointer: ptr, Token: last, Err: err}
+		}
+		i := childIndex(parent, child)
+		if parent.Kind() == Object {
+			return spliceObject(parent, i, i+1), nil
+		}
+		return spliceArray(parent, i, i+1), nil
+	})
+}
+
+// InsertAt returns a copy of v where x is inserted at the given index in the
+// array at the JSON Pointer ptr, shifting the elements from index onwards. The
+// index may be equal to the length of the array to append x. A nil x is
+// treated as JSON null.
+//
+// Returns an error wrapping ErrInvalidPointer if ptr is invalid, or
+// ErrPointerNotFound if it does not refer to an array or index is out of
+// range; the pointer of the error is ptr followed by index.
+func (v *Value) InsertAt(ptr string, index int, x *Value) (*Value, error) {
+	tokens, err := pointerTokens(ptr)
+	if err != nil {
+		return nil, err
+	}
+	return v.edit(ptr, tokens, func(array *Value) (Value, error) {
+		if array.Kind() != Array {
+			return Value{}, insertError(ptr, strconv.Itoa(index), len(tokens), fmt.Errorf("%w: cannot insert in %s value", ErrPointerNotFound, kindName(array.Kind())))
+		}
+		if n := array.Len(); index < 0 || index > n {
+			return Value{}, insertError(ptr, strconv.Itoa(index), len(tokens), fmt.Errorf("%w: index %d out of range for array of length %d", ErrPointerNotFound, index, n))
+		}
+		return spliceArray(array, index, index, valueOrNull(x)), nil
+	})
+}
+
+// AppendAt returns a copy of v where x is appended to the array at the JSON
+// Pointer ptr. A nil x is treated as JSON null.
+//
+// Returns an error wrapping ErrInvalidPointer if ptr is invalid, or
+// ErrPointerNotFound if it does not refer to an array; the pointer of the
+// error is ptr followed by "/-".
+func (v *Value) AppendAt(ptr string, x *Value) (*Value, error) {
+	tokens, err := pointerTokens(ptr)
+	if err != nil {
+		return nil, err
+	}
+	return v.edit(ptr, tokens, func(array *Value) (Value, error) {
+		if array.Kind() != Array {
+			return Value{}, insertError(ptr, "-", len(tokens), fmt.Errorf("%w: cannot append to %s value", ErrPointerNotFound, kindName(array.Kind())))
+		}
+		n := array.Len()
+		return spliceArray(array, n, n, valueOrNull(x)), nil
+	})
+}
+
+// insertError returns the error of InsertAt and AppendAt, where token is the
+// reference token of the position in the array appended to ptr.
+func insertError(ptr, token string, index int, err error) error {
+	return &PointerError{Pointer: ptr + "/" + token, Token: index, Err: err}
+}
+
+// pointerTokens returns the unescaped reference tokens of the JSON Pointer
+// ptr.
+func pointerTokens(ptr string) ([]string, error) {
+	if err := validPointer(ptr); err != nil {
+		return nil, err
+	}
+	var tokens []string
+	for rest := ptr; rest != ""; {
+		var token string
+		token, rest = nextPointerToken(rest)
+		tokens = append(tokens, unescapePointerToken(token))
+	}
+	return tokens, nil
+}
+
+// edit returns a copy of v where the value at the reference tokens, which are
+// the first tokens of the JSON Pointer ptr, is replaced by the result of fn.
+// The arrays and objects along the way are copied, the other values being
+// shared with v.
+func (v *Value) edit(ptr string, tokens []string, fn func(*Value) (Value, error)) (*Value, error) {
+	spine := make([]*Value, len(tokens)+1)
+	spine[0] = v
+	for i, token := range tokens {
+		next, err := resolvePointerToken(spine[i], token)
+		if err != nil {
+			return nil, &PointerError{Pointer: ptr, Token: i, Err: err}
+		}
+		spine[i+1] = next
+	}
+
+	edited, err := fn(spine[len(tokens)])
+	if err != nil {
+		return nil, err
+	}
+	for i := len(tokens) - 1; i >= 0; i-- {
+		parent, child := spine[i], spine[i+1]
+		j := childIndex(parent, child)
+		if parent.Kind() == Object {
+			edited = spliceObject(parent, j, j+1, field{k: parent.fields()[j].k, v: edited})
+		} else {
+			edited = spliceArray(parent, j, j+1, edited)
+		}
+	}
+	return &edited, nil
+}
+
+// childIndex returns the index of child, which is one of the elements or
+// field values of the array or object v.
+func childIndex(v, child *Value) int {
+	if v.Kind() == Array {
+		elems := v.elements()
+		return int((uintptr(unsafe.Pointer(child)) - uintptr(unsafe.Pointer(&elems[0]))) / unsafe.Sizeof(Value{}))
+	}
+	fields := v.fields()
+	return int((uintptr(unsafe.Pointer(child)) - uintptr(unsafe.Pointer(&fields[0].v))) / unsafe.Sizeof(field{}))
+}
+
+// valueOrNull returns a copy of x, or a JSON null if x is nil.
+func valueOrNull(x *Value) Value {
+	if x == nil {
+		return makeNullValue("null")
+	}
+	return *x
+}
+
+// spliceArray returns a copy of the array v where the elements in [i, j) are
+// replaced by insert.
+func spliceArray(v *Value, i, j int, insert ...Value) Value {
+	elems := v.elements()
+	result := make([]Value, 1, 1+len(elems)-(j-i)+len(insert))
+	result = append(result, elems[:i]...)
+	result = append(result, insert...)
+	result = append(result, elems[j:]...)
+
+	b := []byte{'['}
+	for k := 1; k < len(result); k++ {
+		if k > 1 {
+			b = append(b, ',')
+		}
+		b = append(b, result[k].JSON()...)
+	}
+	b = append(b, ']')
+	result[0] = makeStringValue(unsafe.String(unsafe.SliceData(b), len(b)))
+	return makeArrayValue(result)
+}
+
+// spliceObject returns a copy of the object v where the fields in [i, j) are
+// replaced by insert. The key index is copied, only the hashes of the inserted
+// fields being computed.
+func spliceObject(v *Value, i, j int, insert ...field) Value {
+	parsed := v
+	if v.unparsed() {
+		parsed = v.parse()
+	}
+	all := unsafe.Slice((*field)(parsed.p), parsed.len())
+	fields, hashes := all[1:], all[0].k[:len(all)-1]
+
+	result := make([]field, 1, 1+len(fields)-(j-i)+len(insert))
+	result = append(result, fields[:i]...)
+	result = append(result, insert...)
+	result = append(result, fields[j:]...)
+
+	h := make([]byte, 0, len(result)-1)
+	h = append(h, hashes[:i]...)
+	for k := range insert {
+		h = append(h, byte(maphash.String(hashseed, insert[k].k)))
+	}
+	h = append(h, hashes[j:]...)
+
+	b := []byte{'{'}
+	for k := 1; k < len(result); k++ {
+		if k > 1 {
+			b = append(b, ',')
+		}
+		b = AppendQuote(b, result[k].k)
+		b = append(b, ':')
+		b = append(b, result[k].v.JSON()...)
+	}
+	b = append(b, '}')
+	result[0].k = unsafe.String(unsafe.SliceData(h), len(h))
+	result[0].v = makeStringValue(unsafe.String(unsafe.SliceData(b), len(b)))
+	return makeObjectValue(result)
+}
@@ -0,0 +1,169 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestEdit(t *testing.T) {
+	const input = `{"a": {"b": [1, 2, 3], "c": {"d": true}}, "e": "s", "a/b": null}`
+	doc, err := jsonlite.Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := jsonlite.Parse(`{"y": [0]}`)
+
+	tests := []struct {
+		name string
+		edit func(*jsonlite.Value) (*jsonlite.Value, error)
+		want string
+	}{
+		{"replace", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.With("/a/b/1", x) },
+			`{"a":{"b":[1,{"y":[0]},3],"c":{"d":true}},"e":"s","a/b":null}`},
+		{"add", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.With("/a/c/f", nil) },
+			`{"a":{"b":[1,2,3],"c":{"d":true,"f":null}},"e":"s","a/b":null}`},
+		{"escaped", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.With("/a~1b", x) },
+			`{"a":{"b":[1,2,3],"c":{"d":true}},"e":"s","a/b":{"y":[0]}}`},
+		{"root", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.With("", x) },
+			`{"y":[0]}`},
+		{"remove field", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.Without("/a/c") },
+			`{"a":{"b":[1,2,3]},"e":"s","a/b":null}`},
+		{"remove element", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.Without("/a/b/0") },
+			`{"a":{"b":[2,3],"c":{"d":true}},"e":"s","a/b":null}`},
+		{"insert", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.InsertAt("/a/b", 1, x) },
+			`{"a":{"b":[1,{"y":[0]},2,3],"c":{"d":true}},"e":"s","a/b":null}`},
+		{"insert end", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.InsertAt("/a/b", 3, nil) },
+			`{"a":{"b":[1,2,3,null],"c":{"d":true}},"e":"s","a/b":null}`},
+		{"append", func(v *jsonlite.Value) (*jsonlite.Value, error) { return v.AppendAt("/a/b", x) },
+			`{"a":{"b":[1,2,3,{"y":[0]}],"c":{"d":true}},"e":"s","a/b":null}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.edit(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(v.Compact(nil)); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if !jsonlite.Valid(v.JSON()) {
+				t.Errorf("invalid JSON: %s", v.JSON())
+			}
+			// The tree is identical to the one parsed from its JSON text.
+			parsed, err := jsonlite.Parse(v.JSON())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(parsed.Compact(nil)); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			// The original document is unchanged.
+			if doc.JSON() != input {
+				t.Errorf("original document modified: %s", doc.JSON())
+			}
+		})
+	}
+}
+
+func TestEditSharing(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"a": {"b": [[1], 2], "c": {"d": true}}, "e": [3]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := doc.AppendAt("/a/b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Values outside of the modified spine are shared with the original.
+	if v.Lookup("e").Index(0) != doc.Lookup("e").Index(0) {
+		t.Error("expected elements of unchanged arrays to be shared")
+	}
+	if v.Lookup("a").Lookup("c").Lookup("d") != doc.Lookup("a").Lookup("c").Lookup("d") {
+		t.Error("expected fields of unchanged objects to be shared")
+	}
+	if v.Lookup("a").Lookup("b").Index(0).Index(0) != doc.Lookup("a").Lookup("b").Index(0).Index(0) {
+		t.Error("expected unchanged elements of the modified array to be shared")
+	}
+
+	// The original keeps its content, and lookups use the rebuilt key index.
+	if v.Lookup("a").Lookup("b").Len() != 3 || doc.Lookup("a").Lookup("b").Len() != 2 {
+		t.Errorf("unexpected arrays %s and %s", v.JSON(), doc.JSON())
+	}
+	w, err := v.With("/a/z", v.Lookup("e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(w.Lookup("a").Lookup("z").Compact(nil)) != "[3]" || v.Lookup("a").Lookup("z") != nil {
+		t.Errorf("unexpected lookup results in %s", w.JSON())
+	}
+	w, err = w.Without("/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := w.Lookup("a"); a.Lookup("b") != nil || a.Lookup("c") == nil || a.Lookup("z") == nil {
+		t.Errorf("unexpected lookup results in %s", w.JSON())
+	}
+}
+
+func TestEditLazy(t *testing.T) {
+	doc, err := jsonlite.ParseWithOptions(`{"a": [{"b": 1}, {"c": 2}]}`, jsonlite.ParseOptions{LazyDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := doc.With("/a/1/c", doc.Lookup("a").Index(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(v.Compact(nil)); got != `{"a":[{"b":1},{"c":{"b":1}}]}` {
+		t.Errorf("unexpected JSON %s", got)
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"a": [1, 2], "s": "x"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		edit    func() (*jsonlite.Value, error)
+		err     error
+		pointer string
+		token   int
+		message string
+	}{
+		{"invalid", func() (*jsonlite.Value, error) { return doc.With("a", nil) }, jsonlite.ErrInvalidPointer, "a", -1, ""},
+		{"root", func() (*jsonlite.Value, error) { return doc.Without("") }, jsonlite.ErrInvalidPointer, "", -1, ""},
+		{"missing parent", func() (*jsonlite.Value, error) { return doc.With("/x/y", nil) }, jsonlite.ErrPointerNotFound, "/x/y", 0, ""},
+		{"out of range", func() (*jsonlite.Value, error) { return doc.With("/a/2", nil) }, jsonlite.ErrPointerNotFound, "/a/2", 1, ""},
+		{"dash", func() (*jsonlite.Value, error) { return doc.With("/a/-", nil) }, jsonlite.ErrPointerNotFound, "/a/-", 1, ""},
+		{"scalar", func() (*jsonlite.Value, error) { return doc.With("/s/x", nil) }, jsonlite.ErrPointerNotFound, "/s/x", 1, ""},
+		{"missing key", func() (*jsonlite.Value, error) { return doc.Without("/b") }, jsonlite.ErrPointerNotFound, "/b", 0, ""},
+		{"insert range", func() (*jsonlite.Value, error) { return doc.InsertAt("/a", 3, nil) }, jsonlite.ErrPointerNotFound, "/a/3", 1, ""},
+		{"insert negative", func() (*jsonlite.Value, error) { return doc.InsertAt("/a", -1, nil) }, jsonlite.ErrPointerNotFound, "/a/-1", 1, ""},
+		{"insert object", func() (*jsonlite.Value, error) { return doc.InsertAt("", 0, nil) }, jsonlite.ErrPointerNotFound, "/0", 0, "cannot insert in object value"},
+		{"append scalar", func() (*jsonlite.Value, error) { return doc.AppendAt("/s", nil) }, jsonlite.ErrPointerNotFound, "/s/-", 1, "cannot append to string value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.edit()
+			if v != nil {
+				t.Errorf("expected nil value, got %s", v.JSON())
+			}
+			var e *jsonlite.PointerError
+			if !errors.As(err, &e) || !errors.Is(err, tt.err) {
+				t.Fatalf("expected pointer error wrapping %v, got %v", tt.err, err)
+			}
+			if e.Pointer != tt.pointer || e.Token != tt.token {
+				t.Errorf("expected error at %q token %d, got %q token %d", tt.pointer, tt.token, e.Pointer, e.Token)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
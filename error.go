@@ -0,0 +1,521 @@
+package jsonlite
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrTruncated is returned when the input ends before a JSON value is
+	// complete, which usually indicates that it was cut short.
+	ErrTruncated = errors.New("unexpected end of input")
+	// ErrUnexpectedToken is returned when the input contains a token that is
+	// not allowed at its position.
+	ErrUnexpectedToken = errors.New("unexpected token")
+	// ErrInvalidNumber is returned when a number does not follow the JSON
+	// number grammar.
+	ErrInvalidNumber = errors.New("invalid number")
+	// ErrInvalidEscape is returned when a string contains an invalid escape
+	// sequence or an unescaped control character.
+	ErrInvalidEscape = errors.New("invalid string escape")
+	// ErrTrailingData is returned when the input contains data after the
+	// root value.
+	ErrTrailingData = errors.New("unexpected data after root value")
+	// ErrDepthExceeded is returned when the nesting depth of the input
+	// exceeds the configured limit.
+	ErrDepthExceeded = errors.New("maximum nesting depth exceeded")
+	// ErrLimitExceeded is returned when the input exceeds one of the limits
+	// configured in ParseOptions, including the nesting depth.
+	ErrLimitExceeded = errors.New("parse limit exceeded")
+	// ErrInvalidPointer is returned when a string is not a valid JSON
+	// Pointer as defined by RFC 6901.
+	ErrInvalidPointer = errors.New("invalid JSON pointer")
+	// ErrPointerNotFound is returned when a JSON Pointer does not match the
+	// shape of the document it is resolved against.
+	ErrPointerNotFound = errors.New("JSON pointer not found")
+	// ErrInvalidJSONPath is returned when a JSONPath query is malformed or
+	// not well-typed as defined by RFC 9535.
+	ErrInvalidJSONPath = errors.New("invalid JSONPath query")
+	// ErrTypeMismatch is returned when a JSON value cannot be decoded into
+	// a Go value of an incompatible type, or is not of the kind expected by
+	// Iterator.Object or Iterator.Array.
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrUnsupportedValue is returned when a Go value cannot be encoded to
+	// JSON, such as a channel or a NaN.
+	ErrUnsupportedValue = errors.New("unsupported value")
+)
+
+// SyntaxError describes malformed JSON input, or input exceeding the limits
+// configured for the parser.
+//
+// Errors returned by Parse, ParseSeq, Validate and the Iterator when the input
+// is not valid JSON are of type *SyntaxError, and can be inspected with
+// errors.As to locate the problem in the input. A SyntaxError always wraps one
+// of the Err* sentinel errors, which can be tested with errors.Is to classify
+// the problem.
+type SyntaxError struct {
+	// Offset is the byte offset of the offending token in the input.
+	Offset int
+	// Line is the 1-based line number of the offending token.
+	Line int
+	// Column is the 1-based column of the offending token, in bytes.
+	Column int
+	// Token is the offending token, empty if the input ended prematurely.
+	Token string
+	// Path is the JSON path of the value in which the error occurred,
+	// for example $.items[3].price.
+	Path string
+
+	kind   error
+	err    error
+	remain int // length of the input from the offending token to the end
+}
+
+// Error returns a description of the error including its location.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%v at line %d, column %d (%s)", e.err, e.Line, e.Column, e.Path)
+}
+
+// Unwrap returns the sentinel error classifying the problem and the
+// underlying error.
+func (e *SyntaxError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// newSyntaxError constructs a *SyntaxError for token, where rest is the input
+// remaining after the token. Since the position is recorded relative to the end
+// of the input, any suffix of the input can be passed as rest; the absolute
+// position is computed later by locate.
+func newSyntaxError(token, rest string, kind, err error) *SyntaxError {
+	return &SyntaxError{Token: token, kind: kind, err: err, remain: len(token) + len(rest)}
+}
+
+func syntaxErrorf(token, rest string, kind error, msg string, args ...any) *SyntaxError {
+	return newSyntaxError(token, rest, kind, fmt.Errorf(msg, args...))
+}
+
+// LimitError is the error returned when the input exceeds one of the limits
+// of ParseOptions. It unwraps to ErrLimitExceeded.
+//
+// Except for MaxInputBytes, which is checked before parsing, limit errors are
+// wrapped in a *SyntaxError locating the value at which the limit was hit.
+type LimitError struct {
+	// Limit is the name of the ParseOptions field which was exceeded.
+	Limit string
+	// Max is the value of the limit.
+	Max int
+}
+
+// Error returns a description of the limit which was exceeded.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("exceeded %s limit of %d", e.Limit, e.Max)
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *LimitError) Unwrap() error { return ErrLimitExceeded }
+
+// limitError returns the error for token, followed by rest in the input,
+// which exceeds the named limit.
+func limitError(token, rest, limit string, max int) *SyntaxError {
+	return newSyntaxError(token, rest, ErrLimitExceeded, &LimitError{Limit: limit, Max: max})
+}
+
+// depthError returns the error for token, followed by rest in the input,
+// which opens an array or object nested deeper than maxNesting.
+func depthError(token, rest string, maxNesting int) *SyntaxError {
+	return newSyntaxError(token, rest, ErrDepthExceeded, &LimitError{Limit: "MaxNesting", Max: maxNesting})
+}
+
+// truncatedError returns the error for input ending in the middle of a
+// container of the given kind.
+func truncatedError(kind Kind) *SyntaxError {
+	if kind == Array {
+		return syntaxErrorf("", "", ErrTruncated, "unexpected end of array")
+	}
+	return syntaxErrorf("", "", ErrTruncated, "unexpected end of object")
+}
+
+// tokenError returns the error for token, which is not a valid JSON value,
+// and is followed by rest in the input.
+func tokenError(token, rest string) *SyntaxError {
+	switch {
+	case rest == "" && truncatedToken(token):
+		return syntaxErrorf(token, rest, ErrTruncated, "unexpected end of input in %q", token)
+	case token[0] == '"':
+		return syntaxErrorf(token, rest, ErrInvalidEscape, "invalid string: %q", token)
+	case token[0] == '-' || (token[0] >= '0' && token[0] <= '9'):
+		return syntaxErrorf(token, rest, ErrInvalidNumber, "invalid number: %q", token)
+	default:
+		return syntaxErrorf(token, rest, ErrUnexpectedToken, "invalid token: %q", token)
+	}
+}
+
+// keyError returns the error for token, which is not a valid object key,
+// and is followed by rest in the input. The err argument is the error returned
+// when unquoting the key, it may be nil if the key was only validated.
+func keyError(token, rest string, err error) *SyntaxError {
+	switch {
+	case token[0] != '"':
+		return syntaxErrorf(token, rest, ErrUnexpectedToken, "invalid key: %q", token)
+	case rest == "" && truncatedToken(token):
+		return syntaxErrorf(token, rest, ErrTruncated, "unexpected end of input in %q", token)
+	case err == nil:
+		return syntaxErrorf(token, rest, ErrInvalidEscape, "invalid key: %q", token)
+	default:
+		return syntaxErrorf(token, rest, ErrInvalidEscape, "invalid key: %q: %w", token, err)
+	}
+}
+
+// truncatedToken reports whether token could be the beginning of a valid
+// value that was cut short by the end of the input.
+func truncatedToken(token string) bool {
+	switch token[0] {
+	case 'n':
+		return strings.HasPrefix("null", token)
+	case 't':
+		return strings.HasPrefix("true", token)
+	case 'f':
+		return strings.HasPrefix("false", token)
+	case '"':
+		// The tokenizer returns the rest of the input when a string has no
+		// closing quote, which is either missing or escaped.
+		n := 0
+		for i := len(token) - 2; i > 0 && token[i] == '\\'; i-- {
+			n++
+		}
+		return len(token) < 2 || token[len(token)-1] != '"' || n%2 != 0
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return validNumber(token + "0")
+	default:
+		return false
+	}
+}
+
+// locate fills the position of err if it is a *SyntaxError. The json string is
+// the complete input, and start is the offset in json where the top-level
+// value containing the error begins, the path is reconstructed from there.
+func locate(json string, start int, err error) error {
+	if e, ok := err.(*SyntaxError); ok {
+		e.locate(json, start)
+	}
+	return err
+}
+
+func (e *SyntaxError) locate(json string, start int) {
+	e.Offset = len(json) - e.remain
+	e.Line = 1 + strings.Count(json[:e.Offset], "\n")
+	e.Column = 1 + e.Offset - (strings.LastIndexByte(json[:e.Offset], '\n') + 1)
+	e.Path = syntaxPath(json[start:e.Offset])
+}
+
+// shift adjusts the position of e, which was located in a string preceded by
+// offset bytes of the input, containing the given number of lines, the last
+// one being column bytes long.
+func (e *SyntaxError) shift(offset, line, column int) {
+	if e.Line == 1 {
+		e.Column += column
+	}
+	e.Offset += offset
+	e.Line += line
+}
+
+// RecordError is the error yielded by ParseSeqRecover for a record of the
+// sequence which could not be parsed and was skipped.
+type RecordError struct {
+	// Line is the 1-based line number where the record starts.
+	Line int
+	// Start and End are the byte offsets of the skipped part of the input.
+	Start, End int
+	// Err is the error which caused the record to be skipped.
+	Err error
+}
+
+// Error returns a description of the error including the skipped range.
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("skipped record at line %d (bytes %d-%d): %v", e.Line, e.Start, e.End, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RecordError) Unwrap() error { return e.Err }
+
+// syntaxPath reconstructs the JSON path of the value being parsed at the end
+// of prefix by scanning its tokens. This is only done when reporting errors,
+// so the parser does not need to track the path of values it is parsing.
+func syntaxPath(prefix string) string {
+	type frame struct {
+		key    string
+		index  int
+		object bool
+		state  byte // 'k' expecting key, ':' expecting colon, 'v' in value, 'e' after value
+	}
+
+	var stack []frame
+	done := func() {
+		if len(stack) > 0 {
+			stack[len(stack)-1].state = 'e'
+		}
+	}
+
+	tok := Tokenize(prefix)
+	for {
+		token, ok := tok.Next()
+		if !ok {
+			break
+		}
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			switch {
+			case top.object && top.state == 'k' && token[0] == '"':
+				key, err := Unquote(token)
+				if err != nil {
+					key = token
+				}
+				top.key, top.state = key, ':'
+				continue
+			case top.object && top.state == ':' && token == ":":
+				top.state = 'v'
+				continue
+			case token == ",":
+				if top.object {
+					top.state = 'k'
+				} else {
+					top.index, top.state = top.index+1, 'v'
+				}
+				continue
+			}
+		}
+		switch token {
+		case "{":
+			stack = append(stack, frame{object: true, state: 'k'})
+		case "[":
+			stack = append(stack, frame{state: 'v'})
+		case "}", "]":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			done()
+		default:
+			done()
+		}
+	}
+
+	b := []byte{'$'}
+	for _, f := range stack {
+		switch {
+		case f.object && (f.state == ':' || f.state == 'v'):
+			b = appendPathKey(b, f.key)
+		case !f.object && f.state == 'v':
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(f.index), 10)
+			b = append(b, ']')
+		}
+	}
+	return string(b)
+}
+
+// appendPathKey appends a JSON path member selector for key to b, using dot
+// notation when the key is a plain identifier and bracket notation otherwise.
+func appendPathKey(b []byte, key string) []byte {
+	if isIdentifier(key) {
+		b = append(b, '.')
+		return append(b, key...)
+	}
+	b = append(b, '[', '\'')
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; c {
+		case '\'', '\\':
+			b = append(b, '\\', c)
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '\'', ']')
+}
+
+func isIdentifier(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// PointerError is the error returned when a JSON Pointer cannot be parsed
+// or resolved. It wraps ErrInvalidPointer or ErrPointerNotFound.
+type PointerError struct {
+	// Pointer is the JSON Pointer which failed.
+	Pointer string
+	// Token is the index of the reference token of the pointer which could
+	// not be resolved, or -1 if the pointer is invalid.
+	Token int
+	// Err describes the problem.
+	Err error
+}
+
+// Error returns a description of the error.
+func (e *PointerError) Error() string {
+	return fmt.Sprintf("json pointer %q: %v", e.Pointer, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PointerError) Unwrap() error { return e.Err }
+
+// JSONPathError is the error returned when a JSONPath query cannot be
+// compiled. It wraps ErrInvalidJSONPath.
+type JSONPathError struct {
+	// Query is the JSONPath query which failed to compile.
+	Query string
+	// Offset is the byte offset in the query where the problem was found.
+	Offset int
+	// Err describes the problem.
+	Err error
+}
+
+// Error returns a description of the error including its offset.
+func (e *JSONPathError) Error() string {
+	return fmt.Sprintf("jsonpath %q at offset %d: %v", e.Query, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *JSONPathError) Unwrap() error { return e.Err }
+
+// TypeError is the error returned when a JSON value cannot be decoded into a
+// Go value, because their types are incompatible or the value does not fit in
+// the Go type, or is not of the kind expected by the Iterator. It wraps
+// ErrTypeMismatch.
+type TypeError struct {
+	// Path is the JSON path of the value, for example $.items[3].price.
+	Path string
+	// Offset is the byte offset of the value in the input of the iterator
+	// given to DecodeIterator, or in the JSON text of the value given to
+	// Unmarshal. It is -1 for values which are not part of that text, as in
+	// trees edited after parsing.
+	Offset int
+	// Kind is the kind of the JSON value.
+	Kind Kind
+	// Type is the Go type which the value was decoded into, or nil when
+	// Iterator.Object or Iterator.Array was called on a value of another
+	// kind.
+	Type reflect.Type
+	// Err is the underlying error, such as a *strconv.NumError when a number
+	// is out of range, or nil.
+	Err error
+}
+
+// Error returns a description of the error including the path of the value.
+func (e *TypeError) Error() string {
+	if e.Type == nil {
+		return fmt.Sprintf("%v, got %s at %s", e.Err, kindName(e.Kind), e.Path)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("cannot decode JSON %s into %v at %s: %v", kindName(e.Kind), e.Type, e.Path, e.Err)
+	}
+	return fmt.Sprintf("cannot decode JSON %s into %v at %s", kindName(e.Kind), e.Type, e.Path)
+}
+
+// Unwrap returns ErrTypeMismatch and the underlying error.
+func (e *TypeError) Unwrap() []error { return []error{ErrTypeMismatch, e.Err} }
+
+// MarshalError is the error returned when a Go value cannot be encoded to
+// JSON, because its type or value is not supported, or because one of its
+// MarshalJSON or MarshalText methods failed.
+type MarshalError struct {
+	// Path is the JSON path of the value, for example $.items[3].price.
+	Path string
+	// Type is the Go type of the value.
+	Type reflect.Type
+	// Err is the underlying error, which wraps ErrUnsupportedValue when the
+	// type or value is not supported.
+	Err error
+
+	relativePath
+}
+
+// Error returns a description of the error including the path of the value.
+func (e *MarshalError) Error() string {
+	return fmt.Sprintf("cannot encode %v at %s: %v", e.Type, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *MarshalError) Unwrap() error { return e.Err }
+
+// relativePath holds the path of an error returned while encoding a Go value,
+// relative to the value where the error occurred. Segments are
+// added by the enclosing containers as the error returns, innermost first,
+// and the path is resolved once it reaches the root value.
+type relativePath struct {
+	relative bool
+	segments []string
+}
+
+func (p *relativePath) resolve() string {
+	b := []byte{'$'}
+	for i := len(p.segments) - 1; i >= 0; i-- {
+		b = append(b, p.segments[i]...)
+	}
+	p.relative, p.segments = false, nil
+	return string(b)
+}
+
+// pathOf returns the relative path of err, or nil if err is not a
+// *MarshalError with a relative path.
+func pathOf(err error) *relativePath {
+	if e, ok := err.(*MarshalError); ok && e.relative {
+		return &e.relativePath
+	}
+	return nil
+}
+
+// prependKey prepends the member selector of key to the relative path of
+// err, if any.
+func prependKey(err error, key string) error {
+	if p := pathOf(err); p != nil {
+		p.segments = append(p.segments, string(appendPathKey(nil, key)))
+	}
+	return err
+}
+
+// prependIndex prepends the index selector of i to the relative path of err,
+// if any.
+func prependIndex(err error, i int) error {
+	if p := pathOf(err); p != nil {
+		p.segments = append(p.segments, "["+strconv.Itoa(i)+"]")
+	}
+	return err
+}
+
+// resolvePath sets the Path of err if it is relative, and returns err.
+func resolvePath(err error) error {
+	if e, ok := err.(*MarshalError); ok && e.relative {
+		e.Path = e.resolve()
+	}
+	return err
+}
+
+// kindName returns the name of the JSON kind k, as used in error messages.
+func kindName(k Kind) string {
+	switch k {
+	case Null:
+		return "null"
+	case True, False:
+		return "boolean"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Object:
+		return "object"
+	case Array:
+		return "array"
+	default:
+		return fmt.Sprintf("kind %d", int(k))
+	}
+}
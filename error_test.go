@@ -0,0 +1,153 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+var syntaxErrorTests = []struct {
+	name   string
+	input  string
+	offset int
+	line   int
+	column int
+	token  string
+	path   string
+}{
+	{"empty", ``, 0, 1, 1, "", "$"},
+	{"invalid literal", `nul`, 0, 1, 1, "nul", "$"},
+	{"invalid number", `[1, 2, 01]`, 7, 1, 8, "01", "$[2]"},
+	{"missing colon", `{"a" 1}`, 5, 1, 6, "1", "$.a"},
+	{"missing comma", `{"a":1 "b":2}`, 7, 1, 8, `"b"`, "$"},
+	{"trailing comma in array", `[1,]`, 3, 1, 4, "]", "$[1]"},
+	{"unexpected end", `{"a":[1,`, 8, 1, 9, "", "$.a[1]"},
+	{"nested path", `{"items":[{},{},{},{"price":x}]}`, 28, 1, 29, "x", "$.items[3].price"},
+	{"quoted key", `{"a b":{"c":tru}}`, 12, 1, 13, "tru", "$['a b'].c"},
+	{"multiline", "{\n  \"a\": 1,\n  \"b\": nope\n}", 19, 3, 8, "nope", "$.b"},
+	{"trailing data", `{} []`, 3, 1, 4, "[", "$"},
+}
+
+func TestSyntaxError(t *testing.T) {
+	for _, tt := range syntaxErrorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jsonlite.Parse(tt.input)
+			checkSyntaxError(t, err, tt.offset, tt.line, tt.column, tt.token, tt.path)
+		})
+	}
+}
+
+func TestValidateSyntaxError(t *testing.T) {
+	for _, tt := range syntaxErrorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := jsonlite.Validate(tt.input)
+			checkSyntaxError(t, err, tt.offset, tt.line, tt.column, tt.token, tt.path)
+		})
+	}
+}
+
+func TestIteratorSyntaxError(t *testing.T) {
+	input := `{"a":[1,2],"b":{"c":[true,nul]}}`
+	it := jsonlite.Iterate(input)
+	for it.Next() {
+	}
+	checkSyntaxError(t, it.Err(), 26, 1, 27, "nul", "$.b.c[1]")
+}
+
+func TestParseSeqSyntaxError(t *testing.T) {
+	input := "{\"a\":1}\n{\"a\":2}\n{\"a\":[1 2]}\n"
+	var err error
+	for _, e := range jsonlite.ParseSeq(input) {
+		if e != nil {
+			err = e
+		}
+	}
+	checkSyntaxError(t, err, 24, 3, 9, "2", "$.a")
+}
+
+func checkSyntaxError(t *testing.T, err error, offset, line, column int, token, path string) {
+	t.Helper()
+	var syntaxErr *jsonlite.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *SyntaxError, got %T: %v", err, err)
+	}
+	if syntaxErr.Offset != offset {
+		t.Errorf("offset: expected %d, got %d", offset, syntaxErr.Offset)
+	}
+	if syntaxErr.Line != line || syntaxErr.Column != column {
+		t.Errorf("position: expected %d:%d, got %d:%d", line, column, syntaxErr.Line, syntaxErr.Column)
+	}
+	if syntaxErr.Token != token {
+		t.Errorf("token: expected %q, got %q", token, syntaxErr.Token)
+	}
+	if syntaxErr.Path != path {
+		t.Errorf("path: expected %q, got %q", path, syntaxErr.Path)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{``, jsonlite.ErrTruncated},
+		{`{"a":`, jsonlite.ErrTruncated},
+		{`{"a":[1,2`, jsonlite.ErrTruncated},
+		{`{"a":"hel`, jsonlite.ErrTruncated},
+		{`{"na`, jsonlite.ErrTruncated},
+		{`[tr`, jsonlite.ErrTruncated},
+		{`[1.`, jsonlite.ErrTruncated},
+		{`[1,]`, jsonlite.ErrUnexpectedToken},
+		{`{"a" 1}`, jsonlite.ErrUnexpectedToken},
+		{`{1:2}`, jsonlite.ErrUnexpectedToken},
+		{`[nope]`, jsonlite.ErrUnexpectedToken},
+		{`[01]`, jsonlite.ErrInvalidNumber},
+		{`[1.e5]`, jsonlite.ErrInvalidNumber},
+		{`["\x"]`, jsonlite.ErrInvalidEscape},
+		{`{"\x":1}`, jsonlite.ErrInvalidEscape},
+		{`{} {}`, jsonlite.ErrTrailingData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if _, err := jsonlite.Parse(tt.input); !errors.Is(err, tt.err) {
+				t.Errorf("Parse: expected %v, got %v", tt.err, err)
+			}
+			if err := jsonlite.Validate(tt.input); !errors.Is(err, tt.err) {
+				t.Errorf("Validate: expected %v, got %v", tt.err, err)
+			}
+			if tt.input == "" || tt.err == jsonlite.ErrTrailingData {
+				return // the iterator accepts empty input and sequences of values
+			}
+			it := jsonlite.Iterate(tt.input)
+			for it.Next() {
+				if _, err := it.Value(); err != nil {
+					break
+				}
+			}
+			if err := it.Err(); !errors.Is(err, tt.err) {
+				t.Errorf("Iterator: expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestUnquoteSentinelErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{`hello`, jsonlite.ErrUnexpectedToken},
+		{`"\x"`, jsonlite.ErrInvalidEscape},
+		{`"\u12"`, jsonlite.ErrInvalidEscape},
+		{`"\ud83d"`, jsonlite.ErrInvalidEscape},
+		{"\"\x01\\n\"", jsonlite.ErrInvalidEscape},
+	}
+
+	for _, tt := range tests {
+		if _, err := jsonlite.Unquote(tt.input); !errors.Is(err, tt.err) {
+			t.Errorf("Unquote(%q): expected %v, got %v", tt.input, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package jsonlite
+
+import (
+	"slices"
+	"strings"
+)
+
+// Extract returns the values at the given paths in the JSON input, in the
+// spirit of gjson's GetMany, without parsing the whole document.
+//
+// The input is scanned once: subtrees which are not on any of the paths are
+// skipped with the tokenizer, only the values at the end of the paths are
+// parsed, and the scan stops as soon as all the paths are resolved. Like
+// Path.Lookup, the first field is selected when keys are duplicated in an
+// object.
+//
+// The i-th element of the result is the value at paths[i], or nil if it was
+// not found. Since most of the input may not be examined, syntax errors are
+// not reported; a path may resolve to nil when the input is malformed.
+func Extract(json string, paths ...Path) []*Value {
+	values := make([]*Value, len(paths))
+	if len(paths) == 0 {
+		return values
+	}
+
+	p := parsers.Get().(*parser)
+	defer parsers.Put(p)
+	p.configure(&ParseOptions{})
+
+	e := extractor{parser: p, paths: paths, values: values, done: make([]bool, len(paths)), missing: len(paths)}
+	for i := range paths {
+		e.active = append(e.active, i)
+	}
+	e.extract(json, 0, len(paths), 0)
+	return values
+}
+
+// extractor holds the state of Extract. The indexes of the paths matching the
+// value being scanned at each depth are stacked in active, the paths of the
+// innermost value being at the end.
+type extractor struct {
+	parser  *parser
+	paths   []Path
+	values  []*Value
+	done    []bool // whether each path is resolved, values being nil if not found
+	active  []int
+	missing int
+	stop    bool // set when the scan must stop, all paths being resolved or the input malformed
+}
+
+// extract scans the value at the beginning of s, which is at the given depth
+// of the paths in e.active[lo:hi], and returns the input after it.
+func (e *extractor) extract(s string, lo, hi, depth int) string {
+	for _, i := range e.active[lo:hi] {
+		if len(e.paths[i].segments) == depth {
+			return e.materialize(s, lo, hi, depth)
+		}
+	}
+
+	token, rest, ok := nextToken(s)
+	if !ok {
+		e.stop = true
+		return rest
+	}
+	switch token {
+	case "{":
+		return e.extractObject(rest, lo, hi, depth)
+	case "[":
+		return e.extractArray(rest, lo, hi, depth)
+	default:
+		return rest
+	}
+}
+
+// materialize parses the value at the beginning of s, and resolves the rest
+// of the paths in e.active[lo:hi] in the parsed value.
+func (e *extractor) materialize(s string, lo, hi, depth int) string {
+	v, rest, err := e.parser.parseValue(s)
+	if err != nil {
+		e.stop = true
+		return rest
+	}
+	parsed := &v
+	for _, i := range e.active[lo:hi] {
+		e.resolve(i, Path{segments: e.paths[i].segments[depth:]}.Lookup(parsed))
+	}
+	return rest
+}
+
+// resolve sets the value of the i-th path, unless it was already resolved.
+func (e *extractor) resolve(i int, v *Value) {
+	if !e.done[i] {
+		e.values[i], e.done[i] = v, true
+		if e.missing--; e.missing == 0 {
+			e.stop = true
+		}
+	}
+}
+
+func (e *extractor) extractObject(rest string, lo, hi, depth int) string {
+	for {
+		token, next, ok := nextToken(rest)
+		if !ok || token == "}" {
+			e.stop = !ok
+			return next
+		}
+		if token[0] != '"' || len(token) < 2 {
+			e.stop = true
+			return next
+		}
+		colon, after, ok := nextToken(next)
+		if !ok || colon != ":" {
+			e.stop = true
+			return after
+		}
+
+		key := token[1 : len(token)-1]
+		if strings.IndexByte(key, '\\') >= 0 {
+			var err error
+			if key, err = Unquote(token); err != nil {
+				e.stop = true
+				return after
+			}
+		}
+
+		base := len(e.active)
+		for _, i := range e.active[lo:hi] {
+			if e.paths[i].segments[depth].key == key {
+				e.active = append(e.active, i)
+			}
+		}
+		if rest = e.field(after, lo, &hi, base, depth); e.stop {
+			return rest
+		}
+
+		token, rest, ok = nextToken(rest)
+		switch {
+		case token == "}":
+			return rest
+		case token != "," || !ok:
+			e.stop = true
+			return rest
+		case lo == hi:
+			return e.skip(rest, Object)
+		}
+	}
+}
+
+func (e *extractor) extractArray(rest string, lo, hi, depth int) string {
+	for index := 0; ; index++ {
+		if index == 0 {
+			if token, next, ok := nextToken(rest); ok && token == "]" {
+				return next
+			}
+		}
+
+		base := len(e.active)
+		for _, i := range e.active[lo:hi] {
+			if e.paths[i].segments[depth].index == index {
+				e.active = append(e.active, i)
+			}
+		}
+		if rest = e.field(rest, lo, &hi, base, depth); e.stop {
+			return rest
+		}
+
+		token, next, ok := nextToken(rest)
+		switch {
+		case token == "]":
+			return next
+		case token != "," || !ok:
+			e.stop = true
+			return next
+		case lo == hi:
+			return e.skip(next, Array)
+		}
+		rest = next
+	}
+}
+
+// field scans the value of an object field or array element at the beginning
+// of s, extracting it for the paths stacked in e.active[base:], or skipping
+// it if there are none. The paths are then removed from e.active[lo:*hi],
+// since they cannot match any other value.
+func (e *extractor) field(s string, lo int, hi *int, base, depth int) string {
+	if base == len(e.active) {
+		token, rest, ok := nextToken(s)
+		if !ok {
+			e.stop = true
+			return rest
+		}
+		rest, err := e.parser.skipValue(token, rest)
+		if err != nil {
+			e.stop = true
+		}
+		return rest
+	}
+
+	rest := e.extract(s, base, len(e.active), depth+1)
+	for _, i := range e.active[base:] {
+		e.resolve(i, nil) // no other value can match the path
+	}
+
+	matched := e.active[base:]
+	e.active = e.active[:base]
+	n := lo
+	for _, i := range e.active[lo:*hi] {
+		if !slices.Contains(matched, i) {
+			e.active[n] = i
+			n++
+		}
+	}
+	*hi, e.active = n, e.active[:n]
+	return rest
+}
+
+// skip returns the input after the closing bracket of the array or object of
+// the given kind, where s is the input after one of its values.
+func (e *extractor) skip(s string, kind Kind) string {
+	rest, err := e.parser.skipBrackets(s, kind)
+	if err != nil {
+		e.stop = true
+	}
+	return rest
+}
@@ -0,0 +1,124 @@
+package jsonlite_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestExtract(t *testing.T) {
+	const input = `{
+		"id": 42,
+		"user": {"name": "alice", "tags": ["a", "b", {"x": [1, 2]}], "weird\"key": 1, "escaped": true},
+		"items": [{"sku": "A", "qty": 1}, {"sku": "B", "qty": 2}],
+		"dup": 1,
+		"dup": 2,
+		"nested": {"dup": {"a": 1}, "dup": {"b": 2}}
+	}`
+
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{nil, ``}, // checked separately
+		{[]string{"id"}, `42`},
+		{[]string{"user", "name"}, `"alice"`},
+		{[]string{"user", "tags"}, `["a","b",{"x":[1,2]}]`},
+		{[]string{"user", "tags", "1"}, `"b"`},
+		{[]string{"user", "tags", "2", "x", "1"}, `2`},
+		{[]string{"user", "tags", "3"}, ``},
+		{[]string{"user", `weird"key`}, `1`},
+		{[]string{"user", "escaped"}, `true`},
+		{[]string{"items", "1", "sku"}, `"B"`},
+		{[]string{"items", "0"}, `{"sku":"A","qty":1}`},
+		{[]string{"items", "sku"}, ``},
+		{[]string{"id", "x"}, ``},
+		{[]string{"dup"}, `1`},
+		{[]string{"nested", "dup", "b"}, ``},
+		{[]string{"nested", "dup", "a"}, `1`},
+		{[]string{"missing"}, ``},
+	}
+
+	paths := make([]jsonlite.Path, len(tests))
+	for i, tt := range tests {
+		paths[i] = jsonlite.CompilePath(tt.path...)
+	}
+	values := jsonlite.Extract(input, paths...)
+	if len(values) != len(paths) {
+		t.Fatalf("expected %d values, got %d", len(paths), len(values))
+	}
+
+	doc, err := jsonlite.Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests[0].want = string(doc.Compact(nil))
+
+	for i, tt := range tests {
+		got := ""
+		if values[i] != nil {
+			got = string(values[i].Compact(nil))
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %s", strings.Join(tt.path, "."), tt.want, got)
+		}
+
+		// Extracting a single path must give the same result.
+		single := jsonlite.Extract(input, paths[i])[0]
+		if (single == nil) != (values[i] == nil) || (single != nil && string(single.Compact(nil)) != got) {
+			t.Errorf("%s: different result when extracted alone", strings.Join(tt.path, "."))
+		}
+	}
+}
+
+func TestExtractStopsEarly(t *testing.T) {
+	// The input is malformed after the fields being extracted, which is not
+	// noticed since the scan stops once all paths are resolved.
+	values := jsonlite.Extract(`{"a": 1, "b": {"c": [true]}, "d": ???`,
+		jsonlite.CompilePath("b", "c", "0"),
+		jsonlite.CompilePath("a"),
+	)
+	if values[0] == nil || values[0].Kind() != jsonlite.True {
+		t.Errorf("expected true, got %v", values[0])
+	}
+	if values[1] == nil || values[1].Int() != 1 {
+		t.Errorf("expected 1, got %v", values[1])
+	}
+}
+
+func TestExtractMalformed(t *testing.T) {
+	for _, input := range []string{``, `{`, `{"a"`, `{"a":`, `{"a": [1,`, `[1 2]`, `{"a" 1}`, `{1: 2}`} {
+		t.Run(input, func(t *testing.T) {
+			values := jsonlite.Extract(input, jsonlite.CompilePath("a"), jsonlite.CompilePath("1"))
+			for i, v := range values {
+				if v != nil {
+					t.Errorf("values[%d]: expected nil, got %s", i, v.JSON())
+				}
+			}
+		})
+	}
+	if values := jsonlite.Extract(`{}`); len(values) != 0 {
+		t.Errorf("expected no values, got %d", len(values))
+	}
+}
+
+func BenchmarkExtract(b *testing.B) {
+	input := `{"id": 1, "payload": ` + strings.Repeat(`{"k": [1, 2, 3, "abc"], "m": {"n": null}},`, 100) + `{}], "user": {"name": "x"}}`
+	input = strings.Replace(input, `"payload": {`, `"payload": [{`, 1)
+	paths := []jsonlite.Path{jsonlite.CompilePath("id"), jsonlite.CompilePath("user", "name")}
+
+	b.Run("Parse", func(b *testing.B) {
+		for b.Loop() {
+			v, _ := jsonlite.Parse(input)
+			for _, p := range paths {
+				p.Lookup(v)
+			}
+		}
+	})
+	b.Run("Extract", func(b *testing.B) {
+		for b.Loop() {
+			jsonlite.Extract(input, paths...)
+		}
+	})
+}
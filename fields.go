@@ -0,0 +1,229 @@
+package jsonlite
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// structField is a field of a struct type which is decoded from, or encoded
+// to, a JSON object field.
+type structField struct {
+	name      string       // key of the JSON field
+	index     []int        // index sequence of the field, see reflect.Value.FieldByIndex
+	typ       reflect.Type // type of the field
+	omitEmpty bool         // whether the ",omitempty" option was set
+	quoted    bool         // whether the ",string" option was set on a scalar field
+}
+
+// structFields holds the JSON fields of a struct type, in the order of the
+// declaration of the Go fields.
+type structFields struct {
+	list   []structField
+	byName map[string]int
+}
+
+// lookup returns the index in f.list of the field with the given key, trying
+// a case-insensitive match when there is no exact match like encoding/json.
+// Returns -1 if there is no such field.
+func (f *structFields) lookup(key string) int {
+	if i, ok := f.byName[key]; ok {
+		return i
+	}
+	for i := range f.list {
+		if strings.EqualFold(f.list[i].name, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+var structFieldsCache sync.Map // map[reflect.Type]*structFields
+
+// typeFields returns the JSON fields of the struct type t.
+func typeFields(t reflect.Type) *structFields {
+	if f, ok := structFieldsCache.Load(t); ok {
+		return f.(*structFields)
+	}
+	f, _ := structFieldsCache.LoadOrStore(t, newStructFields(t))
+	return f.(*structFields)
+}
+
+// newStructFields computes the JSON fields of the struct type t, following the
+// rules of encoding/json: the name of a field is set by its json tag, fields
+// tagged "-" and unexported fields are ignored, and the fields of embedded
+// structs without a name in their tag are promoted. The ",string" option is
+// only honored on fields of boolean, numeric or string types, or pointers to
+// these types. When several fields have the same name, the least nested one
+// is selected, then the tagged one; the name is dropped if this leaves more
+// than one field.
+func newStructFields(t reflect.Type) *structFields {
+	type candidate struct {
+		structField
+		tagged bool
+	}
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	// Candidates are collected level by level, so they are sorted by depth.
+	var candidates []candidate
+	visited := map[reflect.Type]bool{}
+	for level := []embedded{{typ: t}}; len(level) > 0; {
+		var next []embedded
+		for _, s := range level {
+			if visited[s.typ] {
+				continue
+			}
+			visited[s.typ] = true
+
+			for i := range s.typ.NumField() {
+				sf := s.typ.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(slices.Clip(s.index), i)
+
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+					if name == "" && ft.Kind() == reflect.Struct {
+						next = append(next, embedded{typ: ft, index: index})
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				tagged := name != ""
+				if !tagged {
+					name = sf.Name
+				}
+				candidates = append(candidates, candidate{
+					structField: structField{
+						name:      name,
+						index:     index,
+						typ:       sf.Type,
+						omitEmpty: hasTagOption(opts, "omitempty"),
+						quoted:    hasTagOption(opts, "string") && quotable(sf.Type),
+					},
+					tagged: tagged,
+				})
+			}
+		}
+		level = next
+	}
+
+	// Group the candidates by name, the dominant field of each group being
+	// the first one since the sort is stable.
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(len(a.index), len(b.index)); c != 0 {
+			return c
+		}
+		switch {
+		case a.tagged == b.tagged:
+			return 0
+		case a.tagged:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	fields := &structFields{byName: make(map[string]int)}
+	for i := 0; i < len(candidates); {
+		j := i + 1
+		for j < len(candidates) && candidates[j].name == candidates[i].name {
+			j++
+		}
+		first := candidates[i]
+		if j == i+1 || len(candidates[i+1].index) > len(first.index) || (first.tagged && !candidates[i+1].tagged) {
+			fields.list = append(fields.list, first.structField)
+		}
+		i = j
+	}
+
+	slices.SortFunc(fields.list, func(a, b structField) int { return slices.Compare(a.index, b.index) })
+	for i, f := range fields.list {
+		fields.byName[f.name] = i
+	}
+	return fields
+}
+
+// hasTagOption reports whether the comma-separated options of a struct tag
+// contain the given option.
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// quotable reports whether the ",string" option can be applied to a field of
+// type t.
+func quotable(t reflect.Type) bool {
+	if t.Name() == "" && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	default:
+		return false
+	}
+}
+
+// fieldByIndex returns the field of the struct v with the given index
+// sequence, allocating the embedded structs which are nil pointers. Returns
+// false if one of them cannot be allocated because it is unexported.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// existingFieldByIndex returns the field of the struct v with the given index
+// sequence, or false if one of the embedded structs is a nil pointer.
+func existingFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
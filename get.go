@@ -0,0 +1,363 @@
+package jsonlite
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Get returns the value at path in v, using the path syntax of gjson to ease
+// migrating from github.com/tidwall/gjson. Returns nil if the path does not
+// match the document, or if v is nil.
+//
+// The path is made of components separated by dots:
+//
+//   - Keys select object fields, and numbers select array elements, for
+//     example "friends.1.first".
+//   - The wildcards '*' and '?' in keys match any sequence of characters
+//     and any single character, selecting the first field whose key matches.
+//   - '\' escapes the next character, for example "fav\.movie".
+//   - "#" returns the number of elements of an array, and "#.rest" returns
+//     an array of the results of applying rest to each element, for example
+//     "friends.#.first".
+//   - "#(query)" returns the first array element matching a query, and
+//     "#(query)#" all of them. Queries such as "age>40" or `last=="Murphy"`
+//     compare a path of the element with a value using ==, =, !=, <, <=, >,
+//     >=, % (wildcard match) or !% (wildcard mismatch), while queries made of
+//     a path alone test whether the path exists. The path is empty to compare
+//     the element itself, as in `#(=="fb")`.
+//   - '|' applies the rest of the path to the result of the preceding
+//     components, instead of each of its elements after '#'.
+//
+// Modifiers such as "@reverse" and multipaths are not supported, paths using
+// them return nil.
+//
+// The lengths returned for "#" and the arrays built by "#.rest" and "#(...)#"
+// are allocated on each call, other results point into v.
+func Get(v *Value, path string) *Value {
+	if v == nil || path == "" {
+		return nil
+	}
+	return get(v, path)
+}
+
+// get applies path to v, an empty path selecting v itself.
+func get(v *Value, path string) *Value {
+	for path != "" && v != nil {
+		comp, sep, rest := nextGetComponent(path)
+		switch {
+		case comp == "#":
+			if v.Kind() != Array {
+				return nil
+			}
+			if sep == '.' {
+				return project(v, rest)
+			}
+			n := makeNumberValue(strconv.Itoa(v.Len()))
+			v = &n
+
+		case strings.HasPrefix(comp, "#("):
+			if v.Kind() != Array {
+				return nil
+			}
+			query, all := strings.CutSuffix(comp[2:], "#")
+			query = strings.TrimSuffix(query, ")")
+			lhs, op, rhs := splitGetQuery(query)
+			var matches []*Value
+			for elem := range v.Array {
+				if matchGetQuery(elem, lhs, op, rhs) {
+					if matches = append(matches, elem); !all {
+						break
+					}
+				}
+			}
+			switch {
+			case !all && len(matches) == 0:
+				return nil
+			case !all:
+				v = matches[0]
+			default:
+				a := makeGetArray(matches)
+				if sep == '.' {
+					return project(a, rest)
+				}
+				v = a
+			}
+
+		case strings.HasPrefix(comp, "@"):
+			return nil
+
+		default:
+			v = getChild(v, comp)
+		}
+		path = rest
+	}
+	return v
+}
+
+// project applies the components of path up to the first pipe to each element
+// of the array v, and the rest of the path to the array of results.
+func project(v *Value, path string) *Value {
+	path, rest, _ := cutGetPipe(path)
+	var values []*Value
+	for elem := range v.Array {
+		if r := get(elem, path); r != nil {
+			values = append(values, r)
+		}
+	}
+	return get(makeGetArray(values), rest)
+}
+
+// makeGetArray returns an array of copies of values.
+func makeGetArray(values []*Value) *Value {
+	elements := make([]Value, len(values)+1)
+	b := []byte{'['}
+	for i, v := range values {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = v.Compact(b)
+		elements[i+1] = *v
+	}
+	b = append(b, ']')
+	elements[0] = makeStringValue(string(b))
+	a := makeArrayValue(elements)
+	return &a
+}
+
+// getChild returns the field of v with the given key, which may contain
+// escapes and wildcards, or the element of v at the given index.
+func getChild(v *Value, key string) *Value {
+	switch v.Kind() {
+	case Object:
+		if !strings.ContainsAny(key, `\*?`) {
+			return v.Lookup(key)
+		}
+		if !strings.ContainsAny(key, "*?") {
+			return v.Lookup(unescapeGetKey(key))
+		}
+		for k, field := range v.Object {
+			if matchGetPattern(key, k) {
+				return field
+			}
+		}
+	case Array:
+		i, err := strconv.Atoi(key)
+		if err == nil && i >= 0 && i < v.Len() && key[0] != '+' {
+			return v.Index(i)
+		}
+	}
+	return nil
+}
+
+func unescapeGetKey(key string) string {
+	b := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' && i+1 < len(key) {
+			i++
+		}
+		b = append(b, key[i])
+	}
+	return string(b)
+}
+
+// nextGetComponent returns the first component of path and the separator
+// following it, which is '.', '|', or zero at the end of the path, along with
+// the rest of the path. Separators inside the parentheses of a query do not
+// end the component.
+func nextGetComponent(path string) (comp string, sep byte, rest string) {
+	depth := 0
+	query := strings.HasPrefix(path, "#(")
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '\\':
+			i++
+		case !query:
+			if c == '.' || c == '|' {
+				return path[:i], c, path[i+1:]
+			}
+		case c == '"':
+			i += quotedGetLength(path[i:]) - 1
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (c == '.' || c == '|'):
+			return path[:i], c, path[i+1:]
+		}
+	}
+	return path, 0, ""
+}
+
+// cutGetPipe slices path around its first pipe separator.
+func cutGetPipe(path string) (before, after string, found bool) {
+	for rest := path; rest != ""; {
+		_, sep, next := nextGetComponent(rest)
+		if sep == '|' {
+			i := len(path) - len(next)
+			return path[:i-1], path[i:], true
+		}
+		rest = next
+	}
+	return path, "", false
+}
+
+// quotedGetLength returns the length of the quoted string at the beginning of
+// s, or len(s) if the string is not terminated.
+func quotedGetLength(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// splitGetQuery splits a query into the path of the value to compare, the
+// comparison operator and the value it is compared with. The operator is
+// empty if the query only tests the existence of the path.
+func splitGetQuery(query string) (lhs, op, rhs string) {
+	depth := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; c {
+		case '\\':
+			i++
+		case '"':
+			i += quotedGetLength(query[i:]) - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '=', '!', '<', '>', '%':
+			if depth > 0 {
+				continue
+			}
+			op = query[i : i+1]
+			if i+1 < len(query) {
+				switch query[i : i+2] {
+				case "==", "!=", "<=", ">=", "!%":
+					op = query[i : i+2]
+				}
+			}
+			if op == "!" {
+				continue
+			}
+			return strings.TrimSpace(query[:i]), op, strings.TrimSpace(query[i+len(op):])
+		}
+	}
+	return strings.TrimSpace(query), "", ""
+}
+
+// matchGetQuery reports whether the array element elem matches a query split
+// by splitGetQuery.
+func matchGetQuery(elem *Value, lhs, op, rhs string) bool {
+	v := get(elem, lhs)
+	if v == nil || op == "" {
+		return v != nil
+	}
+
+	if rhs != "" && rhs[0] == '"' {
+		s, err := Unquote(rhs)
+		if err != nil || v.Kind() != String {
+			return false
+		}
+		str := v.String()
+		switch op {
+		case "=", "==":
+			return str == s
+		case "!=":
+			return str != s
+		case "<":
+			return str < s
+		case "<=":
+			return str <= s
+		case ">":
+			return str > s
+		case ">=":
+			return str >= s
+		case "%":
+			return matchGetPattern(s, str)
+		case "!%":
+			return !matchGetPattern(s, str)
+		}
+		return false
+	}
+
+	switch v.Kind() {
+	case Number:
+		f, err := strconv.ParseFloat(rhs, 64)
+		if err != nil {
+			return false
+		}
+		x := v.Float()
+		switch op {
+		case "=", "==":
+			return x == f
+		case "!=":
+			return x != f
+		case "<":
+			return x < f
+		case "<=":
+			return x <= f
+		case ">":
+			return x > f
+		case ">=":
+			return x >= f
+		}
+	case True, False, Null:
+		switch op {
+		case "=", "==":
+			return v.JSON() == rhs
+		case "!=":
+			return v.JSON() != rhs
+		}
+	}
+	return false
+}
+
+// matchGetPattern reports whether s matches pattern, where '*' matches any
+// sequence of characters, '?' any single character, and '\' escapes the next
+// character of the pattern.
+func matchGetPattern(pattern, s string) bool {
+	// Backtracking to the position of the last star is enough since a star
+	// can absorb any input.
+	star, next := -1, 0
+	i, j := 0, 0
+	for j < len(s) {
+		if i < len(pattern) {
+			switch c := pattern[i]; c {
+			case '*':
+				star, next = i, j
+				i++
+				continue
+			case '?':
+				_, n := utf8.DecodeRuneInString(s[j:])
+				i, j = i+1, j+n
+				continue
+			case '\\':
+				if i+1 < len(pattern) && pattern[i+1] == s[j] {
+					i, j = i+2, j+1
+					continue
+				}
+			default:
+				if c == s[j] {
+					i, j = i+1, j+1
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		_, n := utf8.DecodeRuneInString(s[next:])
+		next += n
+		i, j = star+1, next
+	}
+	for i < len(pattern) && pattern[i] == '*' {
+		i++
+	}
+	return i == len(pattern)
+}
@@ -0,0 +1,131 @@
+package jsonlite_test
+
+import (
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+// gjsonDocument is the example document of the gjson documentation.
+const gjsonDocument = `{
+  "name": {"first": "Tom", "last": "Anderson"},
+  "age": 37,
+  "children": ["Sara","Alex","Jack"],
+  "fav.movie": "Deer Hunter",
+  "friends": [
+    {"first": "Dale", "last": "Murphy", "age": 44, "nets": ["ig", "fb", "tw"]},
+    {"first": "Roger", "last": "Craig", "age": 68, "nets": ["fb", "tw"]},
+    {"first": "Jane", "last": "Murphy", "age": 47, "nets": ["ig", "tw"]}
+  ],
+  "flags": [{"on": true, "id": 1}, {"on": false, "id": 2}, {"on": null, "id": 3}]
+}`
+
+func TestGet(t *testing.T) {
+	doc, err := jsonlite.Parse(gjsonDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string // compact JSON of the result, empty if not found
+	}{
+		{`name.last`, `"Anderson"`},
+		{`age`, `37`},
+		{`children`, `["Sara","Alex","Jack"]`},
+		{`children.#`, `3`},
+		{`children.1`, `"Alex"`},
+		{`children.3`, ``},
+		{`children.-1`, ``},
+		{`child*.2`, `"Jack"`},
+		{`c?ildren.0`, `"Sara"`},
+		{`fav\.movie`, `"Deer Hunter"`},
+		{`fav.movie`, ``},
+		{`friends.#.first`, `["Dale","Roger","Jane"]`},
+		{`friends.1.last`, `"Craig"`},
+		{`friends.#.nets.#`, `[3,2,2]`},
+		{`friends.#.nets.0`, `["ig","fb","ig"]`},
+		{`friends.#.missing`, `[]`},
+		{`friends.#(last=="Murphy").first`, `"Dale"`},
+		{`friends.#(last=="Murphy")#.first`, `["Dale","Jane"]`},
+		{`friends.#(age>45)#.last`, `["Craig","Murphy"]`},
+		{`friends.#(age >= 47)#.first`, `["Roger","Jane"]`},
+		{`friends.#(age==44).first`, `"Dale"`},
+		{`friends.#(age=44).first`, `"Dale"`},
+		{`friends.#(age!=44)#.first`, `["Roger","Jane"]`},
+		{`friends.#(first%"D*").last`, `"Murphy"`},
+		{`friends.#(first!%"D*").last`, `"Craig"`},
+		{`friends.#(first%"?oger").age`, `68`},
+		{`friends.#(nets.#(=="fb"))#.first`, `["Dale","Roger"]`},
+		{`friends.#(last=="Nobody").first`, ``},
+		{`friends.#(last=="Nobody")#`, `[]`},
+		{`friends.#(last=="Murphy")#|#`, `2`},
+		{`friends.#(last=="Murphy")#|0.first`, `"Dale"`},
+		{`friends.#.first|1`, `"Roger"`},
+		{`friends.#.first.1`, `[]`},
+		{`friends.#(first=="a.b|c").age`, ``},
+		{`flags.#(on==true).id`, `1`},
+		{`flags.#(on==false).id`, `2`},
+		{`flags.#(on==null).id`, `3`},
+		{`flags.#(on!=true)#.id`, `[2,3]`},
+		{`flags.#(on)#.id`, `[1,2,3]`},
+		{`name|first`, `"Tom"`},
+		{`name.#`, ``},
+		{`age.first`, ``},
+		{`children|@reverse`, ``},
+		{``, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			v := jsonlite.Get(doc, tt.path)
+			got := ""
+			if v != nil {
+				got = string(v.Compact(nil))
+			}
+			if got != tt.want {
+				t.Errorf("Get(%q): expected %s, got %s", tt.path, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetProjectionValues(t *testing.T) {
+	doc, err := jsonlite.Parse(gjsonDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := jsonlite.Get(doc, `friends.#.age`)
+	if v == nil || v.Kind() != jsonlite.Array || v.Len() != 3 {
+		t.Fatalf("expected an array of 3 ages, got %v", v)
+	}
+	if age := v.Index(2).Int(); age != 47 {
+		t.Errorf("expected 47, got %d", age)
+	}
+	if v.JSON() != `[44,68,47]` {
+		t.Errorf("expected [44,68,47], got %s", v.JSON())
+	}
+	if n := jsonlite.Get(doc, `friends.#`); n.Int() != 3 {
+		t.Errorf("expected 3 friends, got %d", n.Int())
+	}
+	if jsonlite.Get(nil, `name`) != nil {
+		t.Error("expected nil result for nil value")
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	doc, err := jsonlite.Parse(gjsonDocument)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Key", func(b *testing.B) {
+		for b.Loop() {
+			jsonlite.Get(doc, `name.last`)
+		}
+	})
+	b.Run("Query", func(b *testing.B) {
+		for b.Loop() {
+			jsonlite.Get(doc, `friends.#(last=="Murphy").first`)
+		}
+	})
+}
@@ -1,12 +1,19 @@
 package jsonlite
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"strconv"
 	"time"
+	"unsafe"
 )
 
+// iteratorBufferSize is the minimum size of the window of the input buffered
+// by iterators reading from an io.Reader.
+const iteratorBufferSize = 32 * 1024
+
 // Iterator provides a streaming interface for traversing JSON values.
 // It automatically handles control tokens (braces, brackets, colons, commas)
 // and presents only the logical JSON values to the caller.
@@ -19,7 +26,26 @@ type Iterator struct {
 	err      error
 	state    []byte // stack of states: 'a' for array, 'o' for object (expecting key), 'v' for object (expecting value)
 	bytes    [16]byte
-	consumed bool // whether the current value has been consumed
+	path     []iteratorFrame // position in each array or object of the state stack
+	frames   [16]iteratorFrame
+	offset   int    // offset of the current token in the input
+	consumed bool   // whether the current value has been consumed
+	parser   parser // parser of arrays and objects returned by Value
+	// State of iterators created by IterateReader. The json field holds the
+	// window of the input in buf, which is never overwritten so tokens and
+	// values remain valid after more data is read.
+	r    io.Reader
+	buf  []byte
+	eof  bool
+	rerr error // error returned by the reader, other than io.EOF
+	// Position of json[0] in the stream, used to locate syntax errors.
+	position position
+}
+
+// iteratorFrame is the position of an iterator in an array or object.
+type iteratorFrame struct {
+	key   string // key of the current field, in objects
+	index int    // index of the current element or field, -1 before the first one
 }
 
 // Iterate creates a new Iterator for the given JSON string.
@@ -29,6 +55,25 @@ func Iterate(json string) *Iterator {
 		json:   json, // Store original JSON
 	}
 	it.state = it.bytes[:0]
+	it.path = it.frames[:0]
+	return it
+}
+
+// IterateReader creates a new Iterator reading a JSON document from r.
+//
+// The input is read through a window which is refilled as the iteration
+// progresses, so memory usage is bounded by the size of the largest token
+// rather than the size of the document, unless Value or Raw is called on
+// large arrays or objects, which are buffered entirely. Arrays and objects
+// which are skipped are streamed through the window.
+//
+// Syntax errors are located relative to the beginning of the stream, but
+// their Path is only reported while the beginning of the document is still
+// buffered. Errors returned by r other than io.EOF are reported by Err when
+// the input ends prematurely.
+func IterateReader(r io.Reader) *Iterator {
+	it := &Iterator{}
+	it.ResetReader(r)
 	return it
 }
 
@@ -41,20 +86,30 @@ func (it *Iterator) Reset(json string) {
 	it.key = ""
 	it.err = nil
 	it.state = it.bytes[:0]
+	it.path = it.frames[:0]
+	it.offset = 0
 	it.consumed = false
+	it.r, it.buf, it.eof, it.rerr = nil, nil, false, nil
+	it.position = position{}
+}
+
+// ResetReader resets the iterator to parse a new JSON document read from r.
+func (it *Iterator) ResetReader(r io.Reader) {
+	it.Reset("")
+	it.r = r
 }
 
 // Next advances the iterator to the next JSON value.
 // Returns true if there is a value to process, false when done or on error.
 func (it *Iterator) Next() bool {
 	for {
-		token, ok := it.tokens.Next()
+		token, ok := it.next()
 		if !ok {
 			if len(it.state) > 0 {
 				if it.top() == 'a' {
-					it.err = errUnexpectedEndOfArray
+					it.setError(truncatedError(Array))
 				} else {
-					it.err = errUnexpectedEndOfObject
+					it.setError(truncatedError(Object))
 				}
 			}
 			return false
@@ -71,6 +126,7 @@ func (it *Iterator) Next() bool {
 				if token == "," {
 					continue
 				}
+				it.setIndex(it.path[len(it.path)-1].index + 1)
 			case 'o': // in object, expecting key or }
 				if token == "}" {
 					it.pop()
@@ -81,17 +137,17 @@ func (it *Iterator) Next() bool {
 				}
 				key, err := Unquote(token)
 				if err != nil {
-					it.setErrorf("invalid key: %q: %w", token, err)
+					it.setError(keyError(token, it.tokens.json, err))
 					return false
 				}
 				it.setKey(key)
-				colon, ok := it.tokens.Next()
+				colon, ok := it.next()
 				if !ok {
-					it.err = errUnexpectedEndOfObject
+					it.setError(truncatedError(Object))
 					return false
 				}
 				if colon != ":" {
-					it.setErrorf("expected ':', got %q", colon)
+					it.setErrorf(colon, "expected ':', got %q", colon)
 					return false
 				}
 				// Change state to expect value
@@ -107,12 +163,70 @@ func (it *Iterator) Next() bool {
 	}
 }
 
+// next returns the next token of the input, reading more data from the
+// reader when the token may continue past the end of the window.
+func (it *Iterator) next() (string, bool) {
+	for {
+		token, rest, ok := nextToken(it.tokens.json)
+		if it.r == nil || it.eof || (ok && completeToken(token, rest)) {
+			it.tokens.json = rest
+			return token, ok
+		}
+		// Reading at least as many bytes as the token contains makes the
+		// work spent scanning long tokens again linear in their size.
+		it.fill(len(it.json)-len(it.tokens.json), max(len(token), 1))
+	}
+}
+
+// completeToken reports whether token, followed by rest in the input, cannot
+// continue after the end of the input.
+func completeToken(token, rest string) bool {
+	switch {
+	case rest != "":
+		return true
+	case token[0] == '"':
+		return !truncatedToken(token)
+	default:
+		// Numbers and literals are only terminated by a delimiter.
+		return len(token) == 1 && isDelimiter(token[0])
+	}
+}
+
+// fill reads at least min bytes from the reader, unless it reaches the end
+// of the input, preserving the window from offset keep, and returns the new
+// offset of this input. The buffer is reallocated rather than overwritten
+// when it is too small to receive the data.
+func (it *Iterator) fill(keep, min int) int {
+	pos := len(it.json) - len(it.tokens.json)
+	if cap(it.buf)-len(it.buf) < max(min, iteratorBufferSize/4) {
+		tail := it.buf[keep:]
+		it.position.advance(it.buf[:keep])
+		buf := make([]byte, len(tail), max(2*len(tail), len(tail)+min, iteratorBufferSize))
+		it.buf = buf[:copy(buf, tail)]
+		pos, keep = pos-keep, 0
+	}
+
+	n, err := io.ReadAtLeast(it.r, it.buf[len(it.buf):cap(it.buf)], min)
+	it.buf = it.buf[:len(it.buf)+n]
+	if err != nil {
+		it.eof = true
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			it.rerr = err
+		}
+	}
+	it.json = unsafe.String(unsafe.SliceData(it.buf), len(it.buf))
+	it.tokens.json = it.json[pos:]
+	return keep
+}
+
 func (it *Iterator) push(state byte) {
 	it.state = append(it.state, state)
+	it.path = append(it.path, iteratorFrame{index: -1})
 }
 
 func (it *Iterator) pop() {
 	it.state = it.state[:len(it.state)-1]
+	it.path = it.path[:len(it.path)-1]
 }
 
 func (it *Iterator) top() byte {
@@ -124,28 +238,64 @@ func (it *Iterator) set(state byte) {
 }
 
 func (it *Iterator) setError(err error) {
-	it.err = err
+	it.err = it.locate(err)
+}
+
+// locate sets the position of err relative to the beginning of the input.
+func (it *Iterator) locate(err error) error {
+	if it.rerr != nil && errors.Is(err, ErrTruncated) {
+		return it.rerr
+	}
+	e, ok := err.(*SyntaxError)
+	if !ok {
+		return err
+	}
+	e.locate(it.json, 0)
+	if it.position.offset > 0 {
+		e.shift(it.position.offset, it.position.line, it.position.column)
+		e.Path = ""
+	}
+	return e
 }
 
-func (it *Iterator) setErrorf(msg string, args ...any) {
-	it.setError(fmt.Errorf(msg, args...))
+// setErrorf sets an unexpected token error for token, which must be the last
+// token read from the input.
+func (it *Iterator) setErrorf(token, msg string, args ...any) {
+	it.setError(syntaxErrorf(token, it.tokens.json, ErrUnexpectedToken, msg, args...))
 }
 
 func (it *Iterator) setKey(key string) {
 	it.key = key
+	f := &it.path[len(it.path)-1]
+	f.key, f.index = key, f.index+1
+}
+
+// setIndex sets the index of the current element of the innermost array.
+func (it *Iterator) setIndex(i int) {
+	it.path[len(it.path)-1].index = i
 }
 
 func (it *Iterator) setToken(token string) bool {
-	kind, err := tokenKind(token)
+	kind, ok := tokenKind(token)
 	it.token = token
 	it.kind = kind
-	it.err = err
+	it.offset = it.position.offset + len(it.json) - len(it.tokens.json) - len(token)
+	it.err = nil
 	it.consumed = false
 
-	if err != nil {
+	if !ok {
+		it.setError(tokenError(token, it.tokens.json))
 		return false
 	}
 
+	switch kind {
+	case Array, Object:
+		if len(it.state) >= DefaultMaxNesting {
+			it.setError(depthError(token, it.tokens.json, DefaultMaxNesting))
+			return false
+		}
+	}
+
 	switch kind {
 	case Array:
 		it.push('a')
@@ -156,72 +306,63 @@ func (it *Iterator) setToken(token string) bool {
 	return true
 }
 
-func (it *Iterator) skipArray() {
-	depth := 1
-	for depth > 0 {
-		token, ok := it.tokens.Next()
-		if !ok {
-			it.setError(errUnexpectedEndOfArray)
-			return
-		}
-		switch token {
-		case "[":
-			depth++
-		case "]":
-			depth--
-		}
-	}
+// skip validates the array or object which is the current value and advances
+// past it. Its tokens are read through the window, which is refilled without
+// retaining the tokens already validated.
+func (it *Iterator) skip() {
+	maxNesting := DefaultMaxNesting - len(it.state) + 1
+	err := validTokens(tokenStream{tok: &it.tokens, it: it}, it.token, maxNesting)
+	it.consumed = true
 	it.pop()
+	if err != nil {
+		it.setError(err)
+	}
 }
 
-func (it *Iterator) skipObject() {
-	depth := 1
-	for depth > 0 {
-		token, ok := it.tokens.Next()
-		if !ok {
-			it.setError(errUnexpectedEndOfObject)
-			return
-		}
-		switch token {
-		case "{":
-			depth++
-		case "}":
-			depth--
-		}
+// raw validates the array or object which is the current value, advances
+// past it, and returns its JSON text, which is buffered entirely.
+func (it *Iterator) raw() string {
+	// The container was already pushed on the iterator stack, the input is
+	// validated again from its opening bracket, which is still in the window
+	// since no token was read after it.
+	offset := it.offset - it.position.offset
+	maxNesting := DefaultMaxNesting - len(it.state) + 1
+	tok := Tokenizer{json: it.json[offset:]}
+	err := valid(&tok, maxNesting)
+	for it.r != nil && !it.eof && errors.Is(err, ErrTruncated) {
+		offset = it.fill(offset, len(it.json)-offset)
+		tok = Tokenizer{json: it.json[offset:]}
+		err = valid(&tok, maxNesting)
 	}
+	it.tokens.json, it.consumed = tok.json, true
 	it.pop()
+	if err != nil {
+		it.setError(err)
+		return ""
+	}
+	return it.json[offset : len(it.json)-len(tok.json)]
 }
 
-func tokenKind(token string) (Kind, error) {
+// tokenKind returns the kind of value starting with token, and whether the
+// token is valid.
+func tokenKind(token string) (Kind, bool) {
 	switch token[0] {
 	case 'n':
-		if token != "null" {
-			return Null, fmt.Errorf("invalid token: %q", token)
-		}
-		return Null, nil
+		return Null, token == "null"
 	case 't':
-		if token != "true" {
-			return Null, fmt.Errorf("invalid token: %q", token)
-		}
-		return True, nil
+		return True, token == "true"
 	case 'f':
-		if token != "false" {
-			return Null, fmt.Errorf("invalid token: %q", token)
-		}
-		return False, nil
+		return False, token == "false"
 	case '"':
-		return String, nil
+		return String, true
 	case '[':
-		return Array, nil
+		return Array, true
 	case '{':
-		return Object, nil
+		return Object, true
 	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		if !validNumber(token) {
-			return Number, fmt.Errorf("invalid number: %q", token)
-		}
-		return Number, nil
+		return Number, validNumber(token)
 	default:
-		return Null, fmt.Errorf("invalid token: %q", token)
+		return Null, false
 	}
 }
 
@@ -238,6 +379,51 @@ func (it *Iterator) Err() error { return it.err }
 // Depth returns the current nesting depth (0 at top level).
 func (it *Iterator) Depth() int { return len(it.state) }
 
+// Path returns the location of the current value in the document, made of
+// the keys and indexes leading to it from the root. The pointer's String
+// method formats it as a JSON Pointer such as "/items/0/name", and Tokens
+// returns its segments.
+//
+// When the current value is an array or object, the path refers to it until
+// its first element is read.
+func (it *Iterator) Path() Pointer {
+	var tokens []string
+	for i, f := range it.path {
+		switch {
+		case f.index < 0:
+			// The container was just opened, it is the current value.
+		case it.state[i] == 'a':
+			tokens = append(tokens, strconv.Itoa(f.index))
+		default:
+			tokens = append(tokens, f.key)
+		}
+	}
+	return Pointer{tokens: tokens}
+}
+
+// jsonPath returns the path of the current value in the syntax of the Path of
+// errors, such as $.items[3].price.
+func (it *Iterator) jsonPath() string {
+	b := []byte{'$'}
+	for i, f := range it.path {
+		switch {
+		case f.index < 0:
+		case it.state[i] == 'a':
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(f.index), 10)
+			b = append(b, ']')
+		default:
+			b = appendPathKey(b, f.key)
+		}
+	}
+	return string(b)
+}
+
+// Offset returns the byte offset in the input of the first token of the
+// current value, which can be used to locate errors detected by the
+// application.
+func (it *Iterator) Offset() int64 { return int64(it.offset) }
+
 // Value parses and returns the current value.
 // For arrays and objects, this consumes all nested tokens and returns the
 // complete parsed structure.
@@ -257,23 +443,22 @@ func (it *Iterator) value() (Value, error) {
 	case String:
 		// Validate the quoted string but store the quoted token
 		if !validString(it.token) {
-			return Value{}, fmt.Errorf("invalid string: %q", it.token)
+			return Value{}, it.locate(tokenError(it.token, it.tokens.json))
 		}
 		return makeStringValue(it.token), nil
-	case Array:
+	case Array, Object:
 		delimi := len(it.token)
 		offset := len(it.json) - len(it.tokens.json) - delimi
-		val, rest, err := parseArray(it.json[offset:], it.tokens.json, DefaultMaxDepth)
-		it.tokens.json, it.consumed = rest, true
-		if err != nil {
-			it.setError(err)
+		// The container was already pushed on the iterator stack, the
+		// parser starts over from its opening bracket.
+		it.parser.configure(&ParseOptions{MaxNesting: DefaultMaxNesting - len(it.state) + 1})
+		val, rest, err := it.parser.parseValue(it.json[offset:])
+		for it.r != nil && !it.eof && errors.Is(err, ErrTruncated) {
+			// The value continues past the window, which is extended until
+			// the value is complete.
+			offset = it.fill(offset, len(it.json)-offset)
+			val, rest, err = it.parser.parseValue(it.json[offset:])
 		}
-		it.pop()
-		return val, err
-	case Object:
-		delimi := len(it.token)
-		offset := len(it.json) - len(it.tokens.json) - delimi
-		val, rest, err := parseObject(it.json[offset:], it.tokens.json, DefaultMaxDepth)
 		it.tokens.json, it.consumed = rest, true
 		if err != nil {
 			it.setError(err)
@@ -285,6 +470,42 @@ func (it *Iterator) value() (Value, error) {
 	}
 }
 
+// Raw returns the JSON text of the current value as it appears in the input,
+// including the nested values of arrays and objects, and advances past it.
+// The value is validated but not parsed; if it is malformed, an empty string
+// is returned and the error is reported by Err.
+//
+// Raw must be called before the value is consumed: it returns an empty string
+// for arrays and objects which were iterated, or returned by Value.
+func (it *Iterator) Raw() string {
+	if it.err != nil || it.consumed {
+		return ""
+	}
+	it.consumed = true
+	switch it.kind {
+	case Array, Object:
+		return it.raw()
+	case String:
+		if !validString(it.token) {
+			it.setError(tokenError(it.token, it.tokens.json))
+			return ""
+		}
+	}
+	return it.token
+}
+
+// Skip advances past the current value, validating it like Raw, and returns
+// the error reported by Err. Arrays and objects are skipped entirely, without
+// being parsed; over an io.Reader, they are not buffered either.
+func (it *Iterator) Skip() error {
+	if it.err != nil || it.consumed || (it.kind != Array && it.kind != Object) {
+		it.Raw()
+		return it.err
+	}
+	it.skip()
+	return it.err
+}
+
 // Null returns true if the current value is null.
 func (it *Iterator) Null() bool { return it.kind == Null }
 
@@ -438,89 +659,91 @@ func (it *Iterator) Time() (time.Time, error) {
 // be automatically skipped.
 // For null values, no iterations occur.
 //
-// Must only be called when Kind() == Object or Kind() == Null.
+// Must only be called when Kind() == Object or Kind() == Null, other kinds
+// yield a *TypeError wrapping ErrTypeMismatch.
 func (it *Iterator) Object(yield func(string, error) bool) {
 	if it.kind == Null {
 		return // null is treated as empty object
 	}
 	if it.kind != Object {
-		it.setErrorf("expected object, got %v", it.kind)
+		it.err = it.typeError(nil, fmt.Errorf("expected object"))
 		yield("", it.err)
 		return
 	}
 	it.consumed = true // mark the object itself as consumed
 	for i := 0; ; i++ {
-		// Auto-consume the previous value if it wasn't consumed
-		if !it.consumed {
-			switch it.kind {
-			case Array:
-				it.consumed = true
-				it.skipArray()
-			case Object:
-				it.consumed = true
-				it.skipObject()
-			}
-		}
-
-		token, ok := it.tokens.Next()
-		if !ok {
-			it.setError(errUnexpectedEndOfObject)
-			yield("", it.err)
+		key, ok, err := it.nextField(i)
+		if err != nil {
+			yield("", err)
 			return
 		}
-
-		if token == "}" {
-			it.pop()
+		if !ok || !yield(key, nil) {
 			return
 		}
+	}
+}
 
-		if i != 0 {
-			if token != "," {
-				it.setErrorf("expected ',', got %q", token)
-				yield("", it.err)
-				return
-			}
-			token, ok = it.tokens.Next()
-			if !ok {
-				it.setError(errUnexpectedEndOfObject)
-				yield("", it.err)
-				return
-			}
+// nextField positions the iterator on the value of the field at index i of
+// the current object, skipping the value of the previous field if it was not
+// consumed, and returns its key. Returns false at the end of the object.
+func (it *Iterator) nextField(i int) (string, bool, error) {
+	// Auto-consume the previous value if it wasn't consumed
+	if !it.consumed {
+		if it.Skip() != nil {
+			return "", false, it.err
 		}
+	}
 
-		key, err := Unquote(token)
-		if err != nil {
-			it.setErrorf("invalid key: %q: %w", token, err)
-			yield("", it.err)
-			return
-		}
+	token, ok := it.next()
+	if !ok {
+		it.setError(truncatedError(Object))
+		return "", false, it.err
+	}
 
-		colon, ok := it.tokens.Next()
-		if !ok {
-			it.setError(errUnexpectedEndOfObject)
-			yield("", it.err)
-			return
-		}
-		if colon != ":" {
-			it.setErrorf("expected ':', got %q", colon)
-			yield("", it.err)
-			return
-		}
+	if token == "}" {
+		it.pop()
+		return "", false, nil
+	}
 
-		value, ok := it.tokens.Next()
+	if i != 0 {
+		if token != "," {
+			it.setErrorf(token, "expected ',', got %q", token)
+			return "", false, it.err
+		}
+		token, ok = it.next()
 		if !ok {
-			it.setError(errUnexpectedEndOfObject)
-			yield("", it.err)
-			return
+			it.setError(truncatedError(Object))
+			return "", false, it.err
 		}
+	}
 
-		it.setKey(key)
-		it.setToken(value)
+	key, err := Unquote(token)
+	if err != nil {
+		it.setError(keyError(token, it.tokens.json, err))
+		return "", false, it.err
+	}
 
-		if !yield(key, nil) {
-			return
-		}
+	colon, ok := it.next()
+	if !ok {
+		it.setError(truncatedError(Object))
+		return "", false, it.err
 	}
+	if colon != ":" {
+		it.setErrorf(colon, "expected ':', got %q", colon)
+		return "", false, it.err
+	}
+
+	value, ok := it.next()
+	if !ok {
+		it.setError(truncatedError(Object))
+		return "", false, it.err
+	}
+
+	it.setKey(key)
+	if !it.setToken(value) {
+		return "", false, it.err
+	}
+	return key, true, nil
 }
 
 // Array iterates over the elements of the current array.
@@ -530,60 +753,67 @@ func (it *Iterator) Object(yield func(string, error) bool) {
 // next iteration, it will be automatically skipped.
 // For null values, no iterations occur.
 //
-// Must only be called when Kind() == Array or Kind() == Null.
+// Must only be called when Kind() == Array or Kind() == Null, other kinds
+// yield a *TypeError wrapping ErrTypeMismatch.
 func (it *Iterator) Array(yield func(int, error) bool) {
 	if it.kind == Null {
 		return // null is treated as empty array
 	}
 	if it.kind != Array {
-		it.setErrorf("expected array, got %v", it.kind)
+		it.err = it.typeError(nil, fmt.Errorf("expected array"))
 		yield(0, it.err)
 		return
 	}
 	it.consumed = true // mark the array itself as consumed
 	for i := 0; ; i++ {
-		// Auto-consume the previous value if it wasn't consumed
-		if !it.consumed {
-			switch it.kind {
-			case Array:
-				it.consumed = true
-				it.skipArray()
-			case Object:
-				it.consumed = true
-				it.skipObject()
-			}
-		}
-
-		token, ok := it.tokens.Next()
-		if !ok {
-			it.setError(errUnexpectedEndOfArray)
-			yield(i, it.err)
+		ok, err := it.nextElement(i)
+		if err != nil {
+			yield(i, err)
 			return
 		}
-
-		if token == "]" {
-			it.pop()
+		if !ok || !yield(i, nil) {
 			return
 		}
+	}
+}
 
-		if i != 0 {
-			if token != "," {
-				it.setErrorf("expected ',', got %q", token)
-				yield(i, it.err)
-				return
-			}
-			token, ok = it.tokens.Next()
-			if !ok {
-				it.setError(errUnexpectedEndOfArray)
-				yield(i, it.err)
-				return
-			}
+// nextElement positions the iterator on the element at index i of the
+// current array, skipping the previous element if it was not consumed.
+// Returns false at the end of the array.
+func (it *Iterator) nextElement(i int) (bool, error) {
+	// Auto-consume the previous value if it wasn't consumed
+	if !it.consumed {
+		if it.Skip() != nil {
+			return false, it.err
 		}
+	}
 
-		it.setToken(token)
+	token, ok := it.next()
+	if !ok {
+		it.setError(truncatedError(Array))
+		return false, it.err
+	}
 
-		if !yield(i, nil) {
-			return
+	if token == "]" {
+		it.pop()
+		return false, nil
+	}
+
+	if i != 0 {
+		if token != "," {
+			it.setErrorf(token, "expected ',', got %q", token)
+			return false, it.err
 		}
+		token, ok = it.next()
+		if !ok {
+			it.setError(truncatedError(Array))
+			return false, it.err
+		}
+	}
+
+	it.setIndex(i)
+	if !it.setToken(token) {
+		return false, it.err
 	}
+	return true, nil
 }
@@ -1,9 +1,13 @@
 package jsonlite_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/parquet-go/jsonlite"
@@ -1701,12 +1705,13 @@ func TestIteratorObjectTypeMismatch(t *testing.T) {
 		name  string
 		input string
 		kind  jsonlite.Kind
+		want  string
 	}{
-		{"string", `"hello"`, jsonlite.String},
-		{"number", `42`, jsonlite.Number},
-		{"array", `[1, 2, 3]`, jsonlite.Array},
-		{"true", `true`, jsonlite.True},
-		{"false", `false`, jsonlite.False},
+		{"string", ` "hello"`, jsonlite.String, "string"},
+		{"number", ` 42`, jsonlite.Number, "number"},
+		{"array", ` [1, 2, 3]`, jsonlite.Array, "array"},
+		{"true", ` true`, jsonlite.True, "boolean"},
+		{"false", ` false`, jsonlite.False, "boolean"},
 	}
 
 	for _, tt := range tests {
@@ -1734,8 +1739,17 @@ func TestIteratorObjectTypeMismatch(t *testing.T) {
 			}
 			if gotError == nil {
 				t.Error("expected error, got nil")
-			} else if got := gotError.Error(); got != fmt.Sprintf("expected object, got %v", tt.kind) {
-				t.Errorf("unexpected error message: %q", got)
+			} else if want := "expected object, got " + tt.want + " at $"; gotError.Error() != want {
+				t.Errorf("expected error %q, got %q", want, gotError.Error())
+			}
+			var typeErr *jsonlite.TypeError
+			if !errors.Is(gotError, jsonlite.ErrTypeMismatch) || !errors.As(gotError, &typeErr) {
+				t.Errorf("expected type error wrapping ErrTypeMismatch, got %v", gotError)
+			} else if typeErr.Offset != 1 || typeErr.Path != "$" || typeErr.Kind != tt.kind {
+				t.Errorf("expected %v value at $ offset 1, got %v at %s offset %d", tt.kind, typeErr.Kind, typeErr.Path, typeErr.Offset)
+			}
+			if errors.Is(gotError, jsonlite.ErrUnexpectedToken) {
+				t.Errorf("expected type mismatch not to be a syntax error, got %v", gotError)
 			}
 		})
 	}
@@ -1746,12 +1760,13 @@ func TestIteratorArrayTypeMismatch(t *testing.T) {
 		name  string
 		input string
 		kind  jsonlite.Kind
+		want  string
 	}{
-		{"string", `"hello"`, jsonlite.String},
-		{"number", `42`, jsonlite.Number},
-		{"object", `{"a": 1}`, jsonlite.Object},
-		{"true", `true`, jsonlite.True},
-		{"false", `false`, jsonlite.False},
+		{"string", ` "hello"`, jsonlite.String, "string"},
+		{"number", ` 42`, jsonlite.Number, "number"},
+		{"object", ` {"a": 1}`, jsonlite.Object, "object"},
+		{"true", ` true`, jsonlite.True, "boolean"},
+		{"false", ` false`, jsonlite.False, "boolean"},
 	}
 
 	for _, tt := range tests {
@@ -1782,8 +1797,143 @@ func TestIteratorArrayTypeMismatch(t *testing.T) {
 			}
 			if gotError == nil {
 				t.Error("expected error, got nil")
-			} else if got := gotError.Error(); got != fmt.Sprintf("expected array, got %v", tt.kind) {
-				t.Errorf("unexpected error message: %q", got)
+			} else if want := "expected array, got " + tt.want + " at $"; gotError.Error() != want {
+				t.Errorf("expected error %q, got %q", want, gotError.Error())
+			}
+			var typeErr *jsonlite.TypeError
+			if !errors.Is(gotError, jsonlite.ErrTypeMismatch) || !errors.As(gotError, &typeErr) {
+				t.Errorf("expected type error wrapping ErrTypeMismatch, got %v", gotError)
+			} else if typeErr.Offset != 1 || typeErr.Path != "$" || typeErr.Kind != tt.kind {
+				t.Errorf("expected %v value at $ offset 1, got %v at %s offset %d", tt.kind, typeErr.Kind, typeErr.Path, typeErr.Offset)
+			}
+			if errors.Is(gotError, jsonlite.ErrUnexpectedToken) {
+				t.Errorf("expected type mismatch not to be a syntax error, got %v", gotError)
+			}
+		})
+	}
+}
+
+func TestIteratorRaw(t *testing.T) {
+	const input = `{"a": [1, {"b": "x\"y"}], "c": {"d": [[]]}, "e": "str", "f": 2.5, "g": null}`
+
+	t.Run("Object", func(t *testing.T) {
+		it := jsonlite.Iterate(input)
+		it.Next()
+		got := map[string]string{}
+		for key, err := range it.Object {
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[key] = it.Raw()
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		want := map[string]string{
+			"a": `[1, {"b": "x\"y"}]`,
+			"c": `{"d": [[]]}`,
+			"e": `"str"`,
+			"f": `2.5`,
+			"g": `null`,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("Next", func(t *testing.T) {
+		// Raw skips the subtree, Next continues with the following value.
+		it := jsonlite.Iterate(input)
+		var got []string
+		for it.Next() {
+			if it.Key() == "a" {
+				got = append(got, it.Raw())
+				continue
+			}
+			if it.Key() == "d" {
+				if err := it.Skip(); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			got = append(got, it.Path().String())
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		want := []string{``, `[1, {"b": "x\"y"}]`, `/c`, `/e`, `/f`, `/g`}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("Consumed", func(t *testing.T) {
+		it := jsonlite.Iterate(`[[1], 2]`)
+		it.Next()
+		if raw := it.Raw(); raw != `[[1], 2]` {
+			t.Errorf("expected the whole array, got %q", raw)
+		}
+		if raw := it.Raw(); raw != "" {
+			t.Errorf("expected empty string for consumed value, got %q", raw)
+		}
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		large := `[` + strings.Repeat(`{"key": "value", "n": [1, 2, 3]}, `, 5000) + `{}]`
+		doc := `{"skip": ` + large + `, "keep": ` + large + `, "last": true}`
+		it := jsonlite.IterateReader(iotest.HalfReader(strings.NewReader(doc)))
+		it.Next()
+		for key, err := range it.Object {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == "keep" {
+				if raw := it.Raw(); raw != large {
+					t.Errorf("unexpected raw value of %d bytes", len(raw))
+				}
+			}
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+	})
+}
+
+func TestIteratorSkipValidates(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{`{"a": [1, tru], "b": 2}`, jsonlite.ErrUnexpectedToken},
+		{`{"a": {"x" 1}, "b": 2}`, jsonlite.ErrUnexpectedToken},
+		{`{"a": [1, 2,], "b": 2}`, jsonlite.ErrUnexpectedToken},
+		{`{"a": [01], "b": 2}`, jsonlite.ErrInvalidNumber},
+		{`{"a": ["\x"], "b": 2}`, jsonlite.ErrInvalidEscape},
+		{`{"a": "\x", "b": 2}`, jsonlite.ErrInvalidEscape},
+		{`{"a": [[1], "b": 2}`, jsonlite.ErrUnexpectedToken},
+		{`{"a": [[1]`, jsonlite.ErrTruncated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			// Values are skipped explicitly, or automatically by Object.
+			for _, explicit := range []bool{true, false} {
+				it := jsonlite.Iterate(tt.input)
+				it.Next()
+				for key, err := range it.Object {
+					if err != nil {
+						break
+					}
+					if key == "a" && explicit {
+						if err := it.Skip(); err == nil {
+							t.Error("expected Skip to fail")
+						}
+						break
+					}
+				}
+				if !errors.Is(it.Err(), tt.err) {
+					t.Errorf("explicit=%t: expected %v, got %v", explicit, tt.err, it.Err())
+				}
 			}
 		})
 	}
@@ -1802,3 +1952,380 @@ func TestIteratorResetClearsState(t *testing.T) {
 		t.Fatalf("expected Array kind, got %v", it.Kind())
 	}
 }
+
+func TestIteratorDepthExceeded(t *testing.T) {
+	it := jsonlite.Iterate(strings.Repeat("[", 1000000))
+	for it.Next() {
+	}
+	if err := it.Err(); !errors.Is(err, jsonlite.ErrDepthExceeded) {
+		t.Errorf("expected ErrDepthExceeded, got %v", err)
+	}
+
+	// Values parsed from a nested position share the limit of the iterator.
+	depth := jsonlite.DefaultMaxNesting - 10
+	it = jsonlite.Iterate(strings.Repeat("[", depth) + strings.Repeat("[", 20) + strings.Repeat("]", depth+20))
+	for it.Depth() < depth && it.Next() {
+	}
+	if _, err := it.Value(); !errors.Is(err, jsonlite.ErrDepthExceeded) {
+		t.Errorf("expected ErrDepthExceeded, got %v", err)
+	}
+}
+
+func TestIteratorPath(t *testing.T) {
+	const input = `{"a": [1, {"b/c": 2, "d": []}], "e~": {"f": null}, "g": 3}`
+	want := []string{
+		` 0`, `/a 6`, `/a/0 7`, `/a/1 10`, `/a/1/b~1c 18`, `/a/1/d 26`,
+		`/e~0 38`, `/e~0/f 44`, `/g 56`,
+	}
+
+	t.Run("Next", func(t *testing.T) {
+		it := jsonlite.Iterate(input)
+		var got []string
+		for it.Next() {
+			got = append(got, fmt.Sprintf("%s %d", it.Path(), it.Offset()))
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("Object", func(t *testing.T) {
+		it := jsonlite.Iterate(input)
+		var got []string
+		var walk func()
+		walk = func() {
+			got = append(got, fmt.Sprintf("%s %d", it.Path(), it.Offset()))
+			switch it.Kind() {
+			case jsonlite.Object:
+				for _, err := range it.Object {
+					if err != nil {
+						t.Fatal(err)
+					}
+					walk()
+				}
+			case jsonlite.Array:
+				for _, err := range it.Array {
+					if err != nil {
+						t.Fatal(err)
+					}
+					walk()
+				}
+			}
+		}
+		if !it.Next() {
+			t.Fatal(it.Err())
+		}
+		walk()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		it := jsonlite.Iterate(input)
+		it.Next()
+		var got []string
+		for key, err := range it.Object {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == "a" {
+				if _, err := it.Value(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got = append(got, it.Path().String())
+		}
+		if want := []string{"/a", "/e~0", "/g"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	it := jsonlite.Iterate(`[[], [["x"]]]`)
+	for it.Next() {
+		if it.Kind() == jsonlite.String {
+			if tokens := it.Path().Tokens(); !reflect.DeepEqual(tokens, []string{"1", "0", "0"}) {
+				t.Errorf("unexpected path segments %q", tokens)
+			}
+		}
+	}
+}
+
+// iteratorEvents walks all the values of it with Next, and returns a
+// description of each of them.
+func iteratorEvents(it *jsonlite.Iterator) []string {
+	var events []string
+	for it.Next() {
+		event := fmt.Sprintf("%d %q %v %s %d", it.Depth(), it.Key(), it.Kind(), it.Path(), it.Offset())
+		switch it.Kind() {
+		case jsonlite.Array, jsonlite.Object:
+		default:
+			v, err := it.Value()
+			if err != nil {
+				return append(events, err.Error())
+			}
+			event += " " + v.JSON()
+		}
+		events = append(events, event)
+	}
+	if it.Err() != nil {
+		events = append(events, it.Err().Error())
+	}
+	return events
+}
+
+func TestIterateReader(t *testing.T) {
+	large := `[` + strings.Repeat(`{"key": "value with \"escapes\"", "n": -12.5e3, "ok": true, "list": [null, false, []]}, `, 2000) + `"` + strings.Repeat("x", 100000) + `"]`
+	inputs := []string{
+		`null`,
+		`  123456  `,
+		`"string"`,
+		`{"a": [1, 2, {"b": null}], "c": "d"}`,
+		large,
+		`{"a": [1, 2}`,
+		`[1, 2`,
+		`[1, tru`,
+		large[:len(large)-1],
+		large[:50000] + `,]`,
+	}
+
+	readers := map[string]func(string) io.Reader{
+		"Reader":        func(s string) io.Reader { return strings.NewReader(s) },
+		"OneByteReader": func(s string) io.Reader { return iotest.OneByteReader(strings.NewReader(s)) },
+		"HalfReader":    func(s string) io.Reader { return iotest.HalfReader(strings.NewReader(s)) },
+		"DataErrReader": func(s string) io.Reader { return iotest.DataErrReader(strings.NewReader(s)) },
+	}
+
+	for i, input := range inputs {
+		want := iteratorEvents(jsonlite.Iterate(input))
+		for name, reader := range readers {
+			if name == "OneByteReader" && len(input) > 1000 {
+				continue
+			}
+			t.Run(fmt.Sprintf("%d/%s", i, name), func(t *testing.T) {
+				got := iteratorEvents(jsonlite.IterateReader(reader(input)))
+				if len(got) != len(want) {
+					t.Fatalf("expected %d events, got %d", len(want), len(got))
+				}
+				for j := range want {
+					if got[j] != want[j] && !strings.Contains(want[j], " at line ") {
+						t.Fatalf("event %d: expected %s, got %s", j, want[j], got[j])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestIterateReaderValue(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`{"header": {"version": 1}, "rows": [`)
+	for i := range 5000 {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"id": %d, "name": "row %d", "values": [%d, %d]}`, i, i, i, i*2)
+	}
+	b.WriteString(`], "footer": {"count": 5000, "nested": [` + strings.Repeat(`{"x": "yyyyyyyy"},`, 10000) + `{}]}}`)
+	input := b.String()
+
+	it := jsonlite.IterateReader(iotest.HalfReader(strings.NewReader(input)))
+	if !it.Next() || it.Kind() != jsonlite.Object {
+		t.Fatalf("expected object, got %v (%v)", it.Kind(), it.Err())
+	}
+	var footer *jsonlite.Value
+	rows := 0
+	for key, err := range it.Object {
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch key {
+		case "rows":
+			for i, err := range it.Array {
+				if err != nil {
+					t.Fatal(err)
+				}
+				row, err := it.Value()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if id := row.Lookup("id").Int(); id != int64(i) {
+					t.Fatalf("expected id %d, got %d", i, id)
+				}
+				rows++
+			}
+		case "footer":
+			v, err := it.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			footer = v
+		}
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if rows != 5000 {
+		t.Errorf("expected 5000 rows, got %d", rows)
+	}
+	if footer == nil || footer.Lookup("count").Int() != 5000 || footer.Lookup("nested").Len() != 10001 {
+		t.Errorf("unexpected footer: %v", footer)
+	}
+}
+
+func TestIterateReaderErrors(t *testing.T) {
+	input := `{"rows": [` + strings.Repeat("1,\n", 50000) + `1}`
+
+	it := jsonlite.IterateReader(strings.NewReader(input))
+	events := iteratorEvents(it)
+	var want *jsonlite.SyntaxError
+	var got *jsonlite.SyntaxError
+	ref := jsonlite.Iterate(input)
+	refEvents := iteratorEvents(ref)
+	if !errors.As(ref.Err(), &want) || !errors.As(it.Err(), &got) {
+		t.Fatalf("expected syntax errors, got %v and %v", ref.Err(), it.Err())
+	}
+	if got.Offset != want.Offset || got.Line != want.Line || got.Column != want.Column || got.Token != want.Token {
+		t.Errorf("expected error at %d:%d (offset %d), got %d:%d (offset %d)", want.Line, want.Column, want.Offset, got.Line, got.Column, got.Offset)
+	}
+	if len(events) != len(refEvents) {
+		t.Errorf("expected %d events, got %d", len(refEvents), len(events))
+	}
+
+	readErr := errors.New("read failed")
+	it = jsonlite.IterateReader(io.MultiReader(strings.NewReader(`[1, 2, `), iotest.ErrReader(readErr)))
+	iteratorEvents(it)
+	if !errors.Is(it.Err(), readErr) {
+		t.Errorf("expected read error, got %v", it.Err())
+	}
+}
+
+// generatedArray is an io.Reader producing a JSON array of n objects without
+// holding the document in memory.
+type generatedArray struct {
+	n, i int
+	buf  []byte
+}
+
+func (g *generatedArray) Read(b []byte) (int, error) {
+	for len(g.buf) < len(b) && g.i <= g.n {
+		switch {
+		case g.i == 0:
+			g.buf = append(g.buf, '[')
+		case g.i == g.n:
+			g.buf = fmt.Appendf(g.buf, `{"id":%d}]`, g.i)
+		default:
+			g.buf = fmt.Appendf(g.buf, `{"id":%d},`, g.i)
+		}
+		g.i++
+	}
+	if len(g.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, g.buf)
+	g.buf = g.buf[:copy(g.buf, g.buf[n:])]
+	return n, nil
+}
+
+func TestIterateReaderLarge(t *testing.T) {
+	const n = 500000
+	it := jsonlite.IterateReader(&generatedArray{n: n})
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	sum := 0
+	for _, err := range it.Array {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, err := range it.Object {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == "id" {
+				id, err := it.Int()
+				if err != nil {
+					t.Fatal(err)
+				}
+				sum += int(id)
+			}
+		}
+	}
+	if want := n * (n + 1) / 2; sum != want {
+		t.Errorf("expected sum of ids %d, got %d", want, sum)
+	}
+}
+
+// windowReader records the size of the largest buffer passed to Read, which
+// reflects the size of the window of an iterator reading from it.
+type windowReader struct {
+	r   io.Reader
+	max int
+}
+
+func (w *windowReader) Read(b []byte) (int, error) {
+	w.max = max(w.max, len(b))
+	return w.r.Read(b)
+}
+
+func TestIterateReaderSkip(t *testing.T) {
+	const n = 200000
+	r := &windowReader{r: io.MultiReader(
+		strings.NewReader(`{"skipped": `), &generatedArray{n: n},
+		strings.NewReader(`, "explicit": `), &generatedArray{n: n},
+		strings.NewReader(`, "raw": [1, {"a": [2]}], "x": 1}`),
+	)}
+	it := jsonlite.IterateReader(r)
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	var keys []string
+	for key, err := range it.Object {
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		switch key {
+		case "explicit":
+			if err := it.Skip(); err != nil {
+				t.Fatal(err)
+			}
+		case "raw":
+			if raw := it.Raw(); raw != `[1, {"a": [2]}]` {
+				t.Errorf("unexpected raw value %q", raw)
+			}
+		case "x":
+			if x, err := it.Int(); err != nil || x != 1 {
+				t.Errorf("expected 1, got %d (%v)", x, err)
+			}
+		}
+	}
+	if strings.Join(keys, ",") != "skipped,explicit,raw,x" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	// The skipped arrays are several megabytes long, but are streamed through
+	// the window instead of being buffered.
+	if r.max > 256*1024 {
+		t.Errorf("expected the window to stay small, got reads of %d bytes", r.max)
+	}
+
+	// Errors in skipped values are located in the stream.
+	input := `{"a": [` + strings.Repeat(`{"b": [1, 2]}, `, 10000) + `{"b": [1 2]}], "c": 1}`
+	it = jsonlite.IterateReader(strings.NewReader(input))
+	it.Next()
+	for _, err := range it.Object {
+		if err != nil {
+			break
+		}
+	}
+	var got, want *jsonlite.SyntaxError
+	if err := jsonlite.Validate(input); !errors.As(err, &want) || !errors.As(it.Err(), &got) {
+		t.Fatalf("expected syntax errors, got %v and %v", err, it.Err())
+	}
+	if got.Offset != want.Offset || !errors.Is(it.Err(), jsonlite.ErrUnexpectedToken) {
+		t.Errorf("expected error at offset %d, got %v", want.Offset, it.Err())
+	}
+}
@@ -0,0 +1,1180 @@
+package jsonlite
+
+import (
+	"fmt"
+	"iter"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONPath is a compiled JSONPath query, as defined by RFC 9535.
+//
+// Queries are compiled once with CompileJSONPath and can then be evaluated
+// against any number of documents, concurrently if needed.
+type JSONPath struct {
+	query    string
+	segments []jpSegment
+}
+
+// CompileJSONPath compiles a JSONPath query such as
+// "$.store.book[?@.price < 10].title".
+//
+// All the syntax of RFC 9535 is supported: the root identifier, child and
+// descendant segments, name, wildcard, index, slice and filter selectors,
+// and the standard functions length, count, match, search and value.
+// Returns an error wrapping ErrInvalidJSONPath if the query is malformed or
+// not well-typed.
+func CompileJSONPath(query string) (*JSONPath, error) {
+	p := jpParser{query: query}
+	segments, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	return &JSONPath{query: query, segments: segments}, nil
+}
+
+// MustCompileJSONPath is like CompileJSONPath but panics if the query cannot
+// be compiled.
+func MustCompileJSONPath(query string) *JSONPath {
+	path, err := CompileJSONPath(query)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// String returns the source text of the query.
+func (path *JSONPath) String() string { return path.query }
+
+// Select returns an iterator over the nodes of v selected by the query, in
+// the order defined by RFC 9535, along with their normalized paths such as
+// $['store']['book'][0].
+func (path *JSONPath) Select(v *Value) iter.Seq2[string, *Value] {
+	return func(yield func(string, *Value) bool) {
+		w := jpWalker{root: v, track: true}
+		w.emit = func(node *Value) bool {
+			return yield(string(appendNormalizedPath(nil, w.path)), node)
+		}
+		w.walk(path.segments, v)
+	}
+}
+
+// Values returns the values of v selected by the query. It is faster than
+// Select since the normalized paths of the nodes are not computed.
+func (path *JSONPath) Values(v *Value) []*Value {
+	var values []*Value
+	w := jpWalker{root: v}
+	w.emit = func(node *Value) bool {
+		values = append(values, node)
+		return true
+	}
+	w.walk(path.segments, v)
+	return values
+}
+
+// jpSegment is a child segment, or a descendant segment if descendant is set.
+type jpSegment struct {
+	selectors  []jpSelector
+	descendant bool
+}
+
+type jpSelectorKind int
+
+const (
+	jpName jpSelectorKind = iota
+	jpWildcard
+	jpIndex
+	jpSlice
+	jpFilter
+)
+
+type jpSelector struct {
+	kind   jpSelectorKind
+	name   string
+	index  int // also the start of slices
+	end    int
+	step   int
+	bounds uint8 // jpHasStart and jpHasEnd
+	filter jpLogical
+}
+
+const (
+	jpHasStart = 1 << iota
+	jpHasEnd
+)
+
+// jpPathElem is an element of the normalized path of a node, either an object
+// key or an array index.
+type jpPathElem struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// jpWalker applies segments to the nodes of a document, calling emit with the
+// nodes resulting from the last segment.
+type jpWalker struct {
+	root  *Value
+	emit  func(*Value) bool
+	path  []jpPathElem
+	track bool // whether the normalized path of the nodes is tracked
+}
+
+// walk applies segments to v, and returns false if the walk was stopped by
+// emit returning false.
+func (w *jpWalker) walk(segments []jpSegment, v *Value) bool {
+	if len(segments) == 0 {
+		return w.emit(v)
+	}
+	if segments[0].descendant {
+		return w.descend(segments[0].selectors, segments[1:], v)
+	}
+	return w.apply(segments[0].selectors, segments[1:], v)
+}
+
+// descend applies selectors to v and all its descendants, in document order.
+func (w *jpWalker) descend(selectors []jpSelector, rest []jpSegment, v *Value) bool {
+	if !w.apply(selectors, rest, v) {
+		return false
+	}
+	switch v.Kind() {
+	case Object:
+		for k, c := range v.Object {
+			if !w.child(k, 0, true, c, func(c *Value) bool { return w.descend(selectors, rest, c) }) {
+				return false
+			}
+		}
+	case Array:
+		i := 0
+		for c := range v.Array {
+			if !w.child("", i, false, c, func(c *Value) bool { return w.descend(selectors, rest, c) }) {
+				return false
+			}
+			i++
+		}
+	}
+	return true
+}
+
+// child calls fn with the child c of the current node, tracking its path.
+func (w *jpWalker) child(key string, index int, isKey bool, c *Value, fn func(*Value) bool) bool {
+	if !w.track {
+		return fn(c)
+	}
+	w.path = append(w.path, jpPathElem{key: key, index: index, isKey: isKey})
+	ok := fn(c)
+	w.path = w.path[:len(w.path)-1]
+	return ok
+}
+
+// apply applies selectors to v, then the rest of the segments to the
+// selected nodes.
+func (w *jpWalker) apply(selectors []jpSelector, rest []jpSegment, v *Value) bool {
+	next := func(c *Value) bool { return w.walk(rest, c) }
+	kind := v.Kind()
+
+	for i := range selectors {
+		sel := &selectors[i]
+		switch {
+		case sel.kind == jpName && kind == Object:
+			if c := v.Lookup(sel.name); c != nil && !w.child(sel.name, 0, true, c, next) {
+				return false
+			}
+		case (sel.kind == jpWildcard || sel.kind == jpFilter) && kind == Object:
+			for k, c := range v.Object {
+				if sel.kind == jpFilter && !sel.filter.test(w.root, c) {
+					continue
+				}
+				if !w.child(k, 0, true, c, next) {
+					return false
+				}
+			}
+		case (sel.kind == jpWildcard || sel.kind == jpFilter) && kind == Array:
+			j := 0
+			for c := range v.Array {
+				if sel.kind != jpFilter || sel.filter.test(w.root, c) {
+					if !w.child("", j, false, c, next) {
+						return false
+					}
+				}
+				j++
+			}
+		case sel.kind == jpIndex && kind == Array:
+			n, j := v.Len(), sel.index
+			if j < 0 {
+				j += n
+			}
+			if j >= 0 && j < n && !w.child("", j, false, v.Index(j), next) {
+				return false
+			}
+		case sel.kind == jpSlice && kind == Array:
+			n := v.Len()
+			lower, upper := sel.slice(n)
+			switch {
+			case sel.step > 0:
+				for j := lower; j < upper; j += sel.step {
+					if !w.child("", j, false, v.Index(j), next) {
+						return false
+					}
+				}
+			case sel.step < 0:
+				for j := upper; lower < j; j += sel.step {
+					if !w.child("", j, false, v.Index(j), next) {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// slice returns the bounds of a slice selector applied to an array of length
+// n, following section 2.3.4.2.2 of RFC 9535.
+func (sel *jpSelector) slice(n int) (lower, upper int) {
+	normalize := func(i int) int {
+		if i >= 0 {
+			return i
+		}
+		return n + i
+	}
+	if sel.step >= 0 {
+		start, end := 0, n
+		if sel.bounds&jpHasStart != 0 {
+			start = normalize(sel.index)
+		}
+		if sel.bounds&jpHasEnd != 0 {
+			end = normalize(sel.end)
+		}
+		return min(max(start, 0), n), min(max(end, 0), n)
+	}
+	start, end := n-1, -n-1
+	if sel.bounds&jpHasStart != 0 {
+		start = normalize(sel.index)
+	}
+	if sel.bounds&jpHasEnd != 0 {
+		end = normalize(sel.end)
+	}
+	return min(max(end, -1), n-1), min(max(start, -1), n-1)
+}
+
+// appendNormalizedPath appends the normalized path made of elems to b.
+func appendNormalizedPath(b []byte, elems []jpPathElem) []byte {
+	b = append(b, '$')
+	for _, e := range elems {
+		if !e.isKey {
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(e.index), 10)
+			b = append(b, ']')
+			continue
+		}
+		b = append(b, '[', '\'')
+		for i := 0; i < len(e.key); i++ {
+			switch c := e.key[i]; c {
+			case '\'', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				if c < 0x20 {
+					const hex = "0123456789abcdef"
+					b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+				} else {
+					b = append(b, c)
+				}
+			}
+		}
+		b = append(b, '\'', ']')
+	}
+	return b
+}
+
+// jpLogical is a logical expression of a filter selector.
+type jpLogical interface {
+	test(root, current *Value) bool
+}
+
+// jpOperand is an expression producing a value which can be compared, or
+// passed to a function.
+type jpOperand interface {
+	eval(root, current *Value) jpValue
+}
+
+// jpValue is the value of an operand. It is either a node of a document or
+// a literal, a number computed by a function, or Nothing when both are unset.
+type jpValue struct {
+	node  *Value
+	num   float64
+	isNum bool
+}
+
+func (x jpValue) nothing() bool { return x.node == nil && !x.isNum }
+
+func (x jpValue) number() (float64, bool) {
+	if x.isNum {
+		return x.num, true
+	}
+	if x.node != nil && x.node.Kind() == Number {
+		return x.node.Float(), true
+	}
+	return 0, false
+}
+
+func (x jpValue) string() (string, bool) {
+	if x.node != nil && x.node.Kind() == String {
+		return x.node.String(), true
+	}
+	return "", false
+}
+
+type (
+	jpOr  []jpLogical
+	jpAnd []jpLogical
+	jpNot struct{ expr jpLogical }
+	// jpExists tests whether a query selects at least one node.
+	jpExists  struct{ query *jpQuery }
+	jpCompare struct {
+		op          string
+		left, right jpOperand
+	}
+	jpLiteral struct{ value *Value }
+)
+
+func (e jpOr) test(root, current *Value) bool {
+	for _, x := range e {
+		if x.test(root, current) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e jpAnd) test(root, current *Value) bool {
+	for _, x := range e {
+		if !x.test(root, current) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *jpNot) test(root, current *Value) bool { return !e.expr.test(root, current) }
+
+func (e *jpExists) test(root, current *Value) bool {
+	found := false
+	e.query.nodes(root, current, func(*Value) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+func (e *jpCompare) test(root, current *Value) bool {
+	left, right := e.left.eval(root, current), e.right.eval(root, current)
+	switch e.op {
+	case "==":
+		return jpEqual(left, right)
+	case "!=":
+		return !jpEqual(left, right)
+	case "<":
+		return jpLess(left, right)
+	case "<=":
+		return jpLess(left, right) || jpEqual(left, right)
+	case ">":
+		return jpLess(right, left)
+	default: // ">="
+		return jpLess(right, left) || jpEqual(left, right)
+	}
+}
+
+func (e *jpLiteral) eval(root, current *Value) jpValue { return jpValue{node: e.value} }
+
+// jpEqual compares values as defined by section 2.3.5.2.2 of RFC 9535.
+func jpEqual(a, b jpValue) bool {
+	if a.nothing() || b.nothing() {
+		return a.nothing() && b.nothing()
+	}
+	if x, ok := a.number(); ok {
+		y, ok := b.number()
+		return ok && x == y
+	}
+	if a.node == nil || b.node == nil {
+		return false
+	}
+	return jpDeepEqual(a.node, b.node)
+}
+
+func jpDeepEqual(a, b *Value) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case Number:
+		return a.Float() == b.Float()
+	case String:
+		return a.String() == b.String()
+	case Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		i := 0
+		for x := range a.Array {
+			if !jpDeepEqual(x, b.Index(i)) {
+				return false
+			}
+			i++
+		}
+		return true
+	case Object:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for k, x := range a.Object {
+			y := b.Lookup(k)
+			if y == nil || !jpDeepEqual(x, y) {
+				return false
+			}
+		}
+		return true
+	default:
+		return true // null, true and false have no content
+	}
+}
+
+// jpLess orders numbers and strings, other values are not ordered.
+func jpLess(a, b jpValue) bool {
+	if x, ok := a.number(); ok {
+		y, ok := b.number()
+		return ok && x < y
+	}
+	if x, ok := a.string(); ok {
+		y, ok := b.string()
+		return ok && x < y
+	}
+	return false
+}
+
+// jpQuery is a relative (@) or absolute ($) query embedded in a filter.
+type jpQuery struct {
+	segments []jpSegment
+	relative bool
+	singular bool // only name and index selectors, one per child segment
+}
+
+func (q *jpQuery) nodes(root, current *Value, emit func(*Value) bool) {
+	w := jpWalker{root: root, emit: emit}
+	if q.relative {
+		w.walk(q.segments, current)
+	} else {
+		w.walk(q.segments, root)
+	}
+}
+
+// eval returns the node selected by a singular query, or Nothing.
+func (q *jpQuery) eval(root, current *Value) jpValue {
+	var x jpValue
+	q.nodes(root, current, func(node *Value) bool {
+		x.node = node
+		return false
+	})
+	return x
+}
+
+// jpType is the type of function parameters and results of section 2.4.1
+// of RFC 9535.
+type jpType int
+
+const (
+	jpValueType jpType = iota
+	jpLogicalType
+	jpNodesType
+)
+
+// jpFunctions declares the parameter and result types of the functions.
+var jpFunctions = map[string]struct {
+	params []jpType
+	result jpType
+}{
+	"length": {[]jpType{jpValueType}, jpValueType},
+	"count":  {[]jpType{jpNodesType}, jpValueType},
+	"match":  {[]jpType{jpValueType, jpValueType}, jpLogicalType},
+	"search": {[]jpType{jpValueType, jpValueType}, jpLogicalType},
+	"value":  {[]jpType{jpNodesType}, jpValueType},
+}
+
+// jpFunction is a call to one of the standard functions.
+type jpFunction struct {
+	name string
+	args []jpOperand
+	// re is the regular expression of match and search when the pattern
+	// is a literal, in which case static is set. Other patterns come from the
+	// documents and are compiled on each use, since caching them would let
+	// the input grow the cache without bounds.
+	re     *regexp.Regexp
+	static bool
+}
+
+func (f *jpFunction) eval(root, current *Value) jpValue {
+	switch f.name {
+	case "length":
+		x := f.args[0].eval(root, current)
+		if x.node == nil {
+			return jpValue{}
+		}
+		switch x.node.Kind() {
+		case String:
+			return jpValue{num: float64(utf8.RuneCountInString(x.node.String())), isNum: true}
+		case Array, Object:
+			return jpValue{num: float64(x.node.Len()), isNum: true}
+		default:
+			return jpValue{}
+		}
+	case "count":
+		n := 0
+		f.args[0].(*jpQuery).nodes(root, current, func(*Value) bool {
+			n++
+			return true
+		})
+		return jpValue{num: float64(n), isNum: true}
+	default: // "value"
+		var x jpValue
+		n := 0
+		f.args[0].(*jpQuery).nodes(root, current, func(node *Value) bool {
+			x.node, n = node, n+1
+			return n < 2
+		})
+		if n != 1 {
+			return jpValue{}
+		}
+		return x
+	}
+}
+
+// test evaluates match and search, which are the functions returning a
+// logical value.
+func (f *jpFunction) test(root, current *Value) bool {
+	s, ok := f.args[0].eval(root, current).string()
+	if !ok {
+		return false
+	}
+	re := f.re
+	if !f.static {
+		pattern, ok := f.args[1].eval(root, current).string()
+		if !ok {
+			return false
+		}
+		re, _ = compileIRegexp(pattern, f.name == "match")
+	}
+	return re != nil && re.MatchString(s)
+}
+
+// compileIRegexp compiles an I-Regexp (RFC 9485) pattern to a Go regular
+// expression. Full matches are anchored at both ends, other patterns match
+// any substring.
+func compileIRegexp(pattern string, full bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	if full {
+		b.WriteString(`\A(?:`)
+	}
+	class := false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(pattern[i])
+		case class:
+			if c == ']' {
+				class = false
+			}
+			b.WriteByte(c)
+		case c == '[':
+			class = true
+			b.WriteByte(c)
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				i++
+				b.WriteByte('^')
+			}
+		case c == '.':
+			// I-Regexp dots do not match line terminators.
+			b.WriteString(`[^\n\r]`)
+		case c == '^' || c == '$':
+			// Anchors are ordinary characters in I-Regexp.
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '(' && i+1 < len(pattern) && pattern[i+1] == '?':
+			// Group flags and non-capturing groups are not part of I-Regexp.
+			return nil, fmt.Errorf("unsupported group syntax in %q", pattern)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if full {
+		b.WriteString(`)\z`)
+	}
+	return regexp.Compile(b.String())
+}
+
+// jpParser parses JSONPath queries.
+type jpParser struct {
+	query string
+	i     int
+}
+
+func (p *jpParser) errorf(format string, args ...any) error {
+	return &JSONPathError{Query: p.query, Offset: p.i, Err: fmt.Errorf("%w: "+format, append([]any{ErrInvalidJSONPath}, args...)...)}
+}
+
+func (p *jpParser) peek() byte {
+	if p.i < len(p.query) {
+		return p.query[p.i]
+	}
+	return 0
+}
+
+func (p *jpParser) consume(s string) bool {
+	if strings.HasPrefix(p.query[p.i:], s) {
+		p.i += len(s)
+		return true
+	}
+	return false
+}
+
+// space skips blank space as defined by RFC 9535.
+func (p *jpParser) space() {
+	for p.i < len(p.query) {
+		switch p.query[p.i] {
+		case ' ', '\t', '\n', '\r':
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+func (p *jpParser) parseQuery() ([]jpSegment, error) {
+	if !p.consume("$") {
+		return nil, p.errorf("query must start with '$'")
+	}
+	segments, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.query) {
+		return nil, p.errorf("unexpected character %q", p.query[p.i])
+	}
+	return segments, nil
+}
+
+// parseSegments parses the segments following a root or current node
+// identifier. Blank space after the last segment is left unconsumed.
+func (p *jpParser) parseSegments() ([]jpSegment, error) {
+	var segments []jpSegment
+	for {
+		start := p.i
+		p.space()
+		var seg jpSegment
+		var err error
+		switch {
+		case p.consume(".."):
+			seg.descendant = true
+			switch c := p.peek(); {
+			case c == '[':
+				seg.selectors, err = p.parseBracketed()
+			case c == '*':
+				p.i++
+				seg.selectors = []jpSelector{{kind: jpWildcard}}
+			case isNameFirst(p.query[p.i:]):
+				seg.selectors = []jpSelector{{kind: jpName, name: p.parseName()}}
+			default:
+				err = p.errorf("expected selector after '..'")
+			}
+		case p.consume("."):
+			switch c := p.peek(); {
+			case c == '*':
+				p.i++
+				seg.selectors = []jpSelector{{kind: jpWildcard}}
+			case isNameFirst(p.query[p.i:]):
+				seg.selectors = []jpSelector{{kind: jpName, name: p.parseName()}}
+			default:
+				err = p.errorf("expected member name or '*' after '.'")
+			}
+		case p.peek() == '[':
+			seg.selectors, err = p.parseBracketed()
+		default:
+			p.i = start
+			return segments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+}
+
+func isNameFirst(s string) bool {
+	if s == "" {
+		return false
+	}
+	c := s[0]
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// parseName parses a member name shorthand.
+func (p *jpParser) parseName() string {
+	start := p.i
+	for p.i < len(p.query) {
+		c := p.query[p.i]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80 {
+			p.i++
+			continue
+		}
+		break
+	}
+	return p.query[start:p.i]
+}
+
+func (p *jpParser) parseBracketed() ([]jpSelector, error) {
+	p.i++ // '['
+	var selectors []jpSelector
+	for {
+		p.space()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+		p.space()
+		switch {
+		case p.consume(","):
+		case p.consume("]"):
+			return selectors, nil
+		default:
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *jpParser) parseSelector() (jpSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		name, err := p.parseString()
+		return jpSelector{kind: jpName, name: name}, err
+	case c == '*':
+		p.i++
+		return jpSelector{kind: jpWildcard}, nil
+	case c == '?':
+		p.i++
+		p.space()
+		filter, err := p.parseLogical()
+		return jpSelector{kind: jpFilter, filter: filter}, err
+	case c == '-' || c == ':' || (c >= '0' && c <= '9'):
+		return p.parseIndexOrSlice()
+	default:
+		return jpSelector{}, p.errorf("invalid selector")
+	}
+}
+
+func (p *jpParser) parseIndexOrSlice() (jpSelector, error) {
+	sel := jpSelector{kind: jpIndex, step: 1}
+	if p.peek() != ':' {
+		n, err := p.parseInt()
+		if err != nil {
+			return sel, err
+		}
+		sel.index, sel.bounds = n, jpHasStart
+		start := p.i
+		p.space()
+		if p.peek() != ':' {
+			p.i = start
+			return sel, nil
+		}
+	}
+	p.i++ // ':'
+	sel.kind = jpSlice
+	p.space()
+	if c := p.peek(); c == '-' || (c >= '0' && c <= '9') {
+		n, err := p.parseInt()
+		if err != nil {
+			return sel, err
+		}
+		sel.end, sel.bounds = n, sel.bounds|jpHasEnd
+		p.space()
+	}
+	if p.consume(":") {
+		p.space()
+		if c := p.peek(); c == '-' || (c >= '0' && c <= '9') {
+			n, err := p.parseInt()
+			if err != nil {
+				return sel, err
+			}
+			sel.step = n
+		}
+	}
+	return sel, nil
+}
+
+// parseInt parses an integer in the range of I-JSON, without leading zeros.
+func (p *jpParser) parseInt() (int, error) {
+	start := p.i
+	if p.peek() == '-' {
+		p.i++
+	}
+	digits := p.i
+	for p.i < len(p.query) && p.query[p.i] >= '0' && p.query[p.i] <= '9' {
+		p.i++
+	}
+	s := p.query[start:p.i]
+	switch {
+	case p.i == digits:
+		return 0, p.errorf("expected integer")
+	case p.query[digits] == '0' && (p.i-digits > 1 || digits > start):
+		p.i = start
+		return 0, p.errorf("invalid integer %q", s)
+	}
+	const maxInt = 1<<53 - 1
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n > maxInt || n < -maxInt {
+		p.i = start
+		return 0, p.errorf("integer %s out of range", s)
+	}
+	return int(n), nil
+}
+
+// parseString parses a single or double quoted string literal.
+func (p *jpParser) parseString() (string, error) {
+	start := p.i
+	quote := p.query[p.i]
+	var b []byte
+	b = append(b, '"')
+	for p.i++; p.i < len(p.query); p.i++ {
+		switch c := p.query[p.i]; {
+		case c == quote:
+			p.i++
+			s, err := Unquote(string(append(b, '"')))
+			if err != nil {
+				p.i = start
+				return "", p.errorf("invalid string literal: %v", err)
+			}
+			return s, nil
+		case c == '\\' && p.i+1 < len(p.query):
+			p.i++
+			switch e := p.query[p.i]; {
+			case e == '\'' && quote == '\'':
+				b = append(b, '\'')
+			case e == '\'' || (e == '"' && quote == '\''):
+				// Only the enclosing quote may be escaped.
+				p.i = start
+				return "", p.errorf("invalid escape in string literal")
+			default:
+				b = append(b, '\\', e)
+			}
+		case c == '"':
+			b = append(b, '\\', '"')
+		default:
+			b = append(b, c)
+		}
+	}
+	p.i = start
+	return "", p.errorf("unterminated string literal")
+}
+
+// parseLogical parses a logical-or expression.
+func (p *jpParser) parseLogical() (jpLogical, error) {
+	var or jpOr
+	for {
+		and, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, and)
+		start := p.i
+		p.space()
+		if !p.consume("||") {
+			p.i = start
+			break
+		}
+		p.space()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *jpParser) parseAnd() (jpLogical, error) {
+	var and jpAnd
+	for {
+		expr, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+		start := p.i
+		p.space()
+		if !p.consume("&&") {
+			p.i = start
+			break
+		}
+		p.space()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// parseBasic parses a parenthesized expression, a comparison, or a test.
+func (p *jpParser) parseBasic() (jpLogical, error) {
+	if p.consume("!") {
+		p.space()
+		var expr jpLogical
+		var err error
+		if p.peek() == '(' {
+			expr, err = p.parseParen()
+		} else {
+			start := p.i
+			var x any
+			if x, err = p.parsePrimary(); err == nil {
+				expr, err = p.testExpr(x, start)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &jpNot{expr: expr}, nil
+	}
+	if p.peek() == '(' {
+		return p.parseParen()
+	}
+
+	start := p.i
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	end := p.i
+	p.space()
+	op := ""
+	for _, o := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		p.i = end
+		return p.testExpr(left, start)
+	}
+	l, err := p.comparable(left, start)
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	start = p.i
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	r, err := p.comparable(right, start)
+	if err != nil {
+		return nil, err
+	}
+	return &jpCompare{op: op, left: l, right: r}, nil
+}
+
+func (p *jpParser) parseParen() (jpLogical, error) {
+	p.i++ // '('
+	p.space()
+	expr, err := p.parseLogical()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	if !p.consume(")") {
+		return nil, p.errorf("expected ')'")
+	}
+	return expr, nil
+}
+
+// testExpr checks that x, parsed at offset start, can be used as a test.
+func (p *jpParser) testExpr(x any, start int) (jpLogical, error) {
+	switch x := x.(type) {
+	case *jpQuery:
+		return &jpExists{query: x}, nil
+	case *jpFunction:
+		if jpFunctions[x.name].result == jpValueType {
+			p.i = start
+			return nil, p.errorf("result of %s() must be compared", x.name)
+		}
+		return x, nil
+	default:
+		p.i = start
+		return nil, p.errorf("literal must be compared")
+	}
+}
+
+// comparable checks that x, parsed at offset start, can be compared.
+func (p *jpParser) comparable(x any, start int) (jpOperand, error) {
+	switch x := x.(type) {
+	case *jpQuery:
+		if !x.singular {
+			p.i = start
+			return nil, p.errorf("query must be singular to be compared")
+		}
+		return x, nil
+	case *jpFunction:
+		if jpFunctions[x.name].result != jpValueType {
+			p.i = start
+			return nil, p.errorf("result of %s() cannot be compared", x.name)
+		}
+		return x, nil
+	default:
+		return x.(*jpLiteral), nil
+	}
+}
+
+// parsePrimary parses a literal, a query, or a function call, returning a
+// *jpLiteral, *jpQuery or *jpFunction.
+func (p *jpParser) parsePrimary() (any, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.i++
+		segments, err := p.parseSegments()
+		if err != nil {
+			return nil, err
+		}
+		q := &jpQuery{segments: segments, relative: c == '@', singular: true}
+		for _, seg := range segments {
+			if seg.descendant || len(seg.selectors) != 1 || (seg.selectors[0].kind != jpName && seg.selectors[0].kind != jpIndex) {
+				q.singular = false
+			}
+		}
+		return q, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		v := makeStringValue(Quote(s))
+		return &jpLiteral{value: &v}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case c >= 'a' && c <= 'z':
+		start := p.i
+		for p.i < len(p.query) {
+			c := p.query[p.i]
+			if c == '_' || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+				p.i++
+				continue
+			}
+			break
+		}
+		name := p.query[start:p.i]
+		if p.peek() == '(' {
+			return p.parseFunction(name, start)
+		}
+		switch name {
+		case "true", "false", "null":
+			v, _ := Parse(name)
+			return &jpLiteral{value: v}, nil
+		}
+		p.i = start
+		return nil, p.errorf("unexpected %q", name)
+	default:
+		return nil, p.errorf("expected literal, query or function")
+	}
+}
+
+func (p *jpParser) parseNumber() (*jpLiteral, error) {
+	start := p.i
+	if p.peek() == '-' {
+		p.i++
+	}
+	for p.i < len(p.query) {
+		c := p.query[p.i]
+		if (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' || ((c == '+' || c == '-') && (p.query[p.i-1] == 'e' || p.query[p.i-1] == 'E')) {
+			p.i++
+			continue
+		}
+		break
+	}
+	s := p.query[start:p.i]
+	if !validNumber(s) {
+		p.i = start
+		return nil, p.errorf("invalid number %q", s)
+	}
+	v := makeNumberValue(s)
+	return &jpLiteral{value: &v}, nil
+}
+
+func (p *jpParser) parseFunction(name string, start int) (*jpFunction, error) {
+	decl, ok := jpFunctions[name]
+	if !ok {
+		p.i = start
+		return nil, p.errorf("unknown function %s()", name)
+	}
+	f := &jpFunction{name: name}
+	p.i++ // '('
+	p.space()
+	for p.peek() != ')' {
+		if len(f.args) > 0 {
+			if !p.consume(",") {
+				return nil, p.errorf("expected ',' or ')'")
+			}
+			p.space()
+		}
+		argStart := p.i
+		x, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if len(f.args) == len(decl.params) {
+			p.i = argStart
+			return nil, p.errorf("too many arguments for %s()", name)
+		}
+		arg, err := p.argument(x, decl.params[len(f.args)], argStart)
+		if err != nil {
+			return nil, err
+		}
+		f.args = append(f.args, arg)
+		p.space()
+	}
+	p.i++ // ')'
+	if len(f.args) != len(decl.params) {
+		return nil, p.errorf("%s() expects %d arguments, got %d", name, len(decl.params), len(f.args))
+	}
+	if name == "match" || name == "search" {
+		if lit, ok := f.args[1].(*jpLiteral); ok {
+			if pattern, ok := (jpValue{node: lit.value}).string(); ok {
+				// Invalid patterns leave re nil and never match.
+				f.re, _ = compileIRegexp(pattern, name == "match")
+				f.static = true
+			}
+		}
+	}
+	return f, nil
+}
+
+// argument checks that x, parsed at offset start, is a valid argument for a
+// parameter of type t.
+func (p *jpParser) argument(x any, t jpType, start int) (jpOperand, error) {
+	if t == jpNodesType {
+		if q, ok := x.(*jpQuery); ok {
+			return q, nil
+		}
+		p.i = start
+		return nil, p.errorf("function argument must be a query")
+	}
+	return p.comparable(x, start)
+}
@@ -0,0 +1,460 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+// rfc9535Document is the example document of RFC 9535, section 1.5.
+const rfc9535Document = `{ "store": {
+	"book": [
+		{ "category": "reference",
+			"author": "Nigel Rees",
+			"title": "Sayings of the Century",
+			"price": 8.95
+		},
+		{ "category": "fiction",
+			"author": "Evelyn Waugh",
+			"title": "Sword of Honour",
+			"price": 12.99
+		},
+		{ "category": "fiction",
+			"author": "Herman Melville",
+			"title": "Moby Dick",
+			"isbn": "0-553-21311-3",
+			"price": 8.99
+		},
+		{ "category": "fiction",
+			"author": "J. R. R. Tolkien",
+			"title": "The Lord of the Rings",
+			"isbn": "0-395-19395-8",
+			"price": 22.99
+		}
+	],
+	"bicycle": {
+		"color": "red",
+		"price": 399
+	}
+} }`
+
+// selectJSONPath compiles query and returns the compact JSON of the nodes it
+// selects in doc, one per line, prefixed with their normalized path.
+func selectJSONPath(t *testing.T, doc *jsonlite.Value, query string) string {
+	t.Helper()
+	path, err := jsonlite.CompileJSONPath(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for p, v := range path.Select(doc) {
+		lines = append(lines, p+" "+string(v.Compact(nil)))
+	}
+	if got, want := len(path.Values(doc)), len(lines); got != want {
+		t.Errorf("JSONPath.Values: expected %d values, got %d", want, got)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestJSONPath(t *testing.T) {
+	doc, err := jsonlite.Parse(rfc9535Document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`$`, []string{`$ ` + string(doc.Compact(nil))}},
+		{`$.store.book[*].author`, []string{
+			`$['store']['book'][0]['author'] "Nigel Rees"`,
+			`$['store']['book'][1]['author'] "Evelyn Waugh"`,
+			`$['store']['book'][2]['author'] "Herman Melville"`,
+			`$['store']['book'][3]['author'] "J. R. R. Tolkien"`,
+		}},
+		{`$..author`, []string{
+			`$['store']['book'][0]['author'] "Nigel Rees"`,
+			`$['store']['book'][1]['author'] "Evelyn Waugh"`,
+			`$['store']['book'][2]['author'] "Herman Melville"`,
+			`$['store']['book'][3]['author'] "J. R. R. Tolkien"`,
+		}},
+		{`$.store.*.price`, []string{
+			`$['store']['bicycle']['price'] 399`,
+		}},
+		{`$.store..price`, []string{
+			`$['store']['book'][0]['price'] 8.95`,
+			`$['store']['book'][1]['price'] 12.99`,
+			`$['store']['book'][2]['price'] 8.99`,
+			`$['store']['book'][3]['price'] 22.99`,
+			`$['store']['bicycle']['price'] 399`,
+		}},
+		{`$..book[2].title`, []string{
+			`$['store']['book'][2]['title'] "Moby Dick"`,
+		}},
+		{`$..book[-1].title`, []string{
+			`$['store']['book'][3]['title'] "The Lord of the Rings"`,
+		}},
+		{`$..book[0,1].title`, []string{
+			`$['store']['book'][0]['title'] "Sayings of the Century"`,
+			`$['store']['book'][1]['title'] "Sword of Honour"`,
+		}},
+		{`$..book[:2].title`, []string{
+			`$['store']['book'][0]['title'] "Sayings of the Century"`,
+			`$['store']['book'][1]['title'] "Sword of Honour"`,
+		}},
+		{`$..book[?@.isbn].title`, []string{
+			`$['store']['book'][2]['title'] "Moby Dick"`,
+			`$['store']['book'][3]['title'] "The Lord of the Rings"`,
+		}},
+		{`$..book[?@.price<10].title`, []string{
+			`$['store']['book'][0]['title'] "Sayings of the Century"`,
+			`$['store']['book'][2]['title'] "Moby Dick"`,
+		}},
+		{`$["store"]['bicycle'] [ 'color' , "price" ]`, []string{
+			`$['store']['bicycle']['color'] "red"`,
+			`$['store']['bicycle']['price'] 399`,
+		}},
+		{`$.store.book[?@.price > $.store.bicycle.price]`, nil},
+		{`$.store.book[?@.author == "Herman Melville" && @.price <= 8.99].isbn`, []string{
+			`$['store']['book'][2]['isbn'] "0-553-21311-3"`,
+		}},
+		{`$.store.book[?!(@.category == 'fiction' || @.price > 10)].title`, []string{
+			`$['store']['book'][0]['title'] "Sayings of the Century"`,
+		}},
+		{`$.missing`, nil},
+		{`$.store.book.title`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got, want := selectJSONPath(t, doc, tt.query), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestJSONPathSlices(t *testing.T) {
+	doc, err := jsonlite.Parse(`["a","b","c","d","e","f","g"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`$[1:3]`, `"b","c"`},
+		{`$[5:]`, `"f","g"`},
+		{`$[1:5:2]`, `"b","d"`},
+		{`$[5:1:-2]`, `"f","d"`},
+		{`$[::-1]`, `"g","f","e","d","c","b","a"`},
+		{`$[-2:]`, `"f","g"`},
+		{`$[:-5]`, `"a","b"`},
+		{`$[-100:100]`, `"a","b","c","d","e","f","g"`},
+		{`$[1:5:0]`, ``},
+		{`$[3:1]`, ``},
+		{`$[ 1 : 3 : 1 ]`, `"b","c"`},
+		{`$[0, 0]`, `"a","a"`},
+		{`$[7]`, ``},
+		{`$[-8]`, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			path, err := jsonlite.CompileJSONPath(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var values []string
+			for _, v := range path.Values(doc) {
+				values = append(values, string(v.Compact(nil)))
+			}
+			if got := strings.Join(values, ","); got != tt.want {
+				t.Errorf("expected [%s], got [%s]", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONPathFilters(t *testing.T) {
+	doc, err := jsonlite.Parse(`{
+		"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}],
+		"o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}},
+		"e": [{"x": [1, 2]}, {"x": [1, 2, 3]}, {"x": {"y": null}}, {"x": "é"}]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`$.a[?@.b == 'kilo']`, `{"b":"kilo"}`},
+		{`$.a[?@>3.5]`, `5,4,6`},
+		{`$.a[?@.b]`, `{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}`},
+		{`$.a[?@.b == $.x]`, `3,5,1,2,4,6`},
+		{`$.a[?@ == @]`, `3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}`},
+		{`$.a[?@.b < 'kilo']`, `{"b":"j"},{"b":"k"}`},
+		{`$.a[?@.b >= 'k']`, `{"b":"k"},{"b":"kilo"}`},
+		{`$.a[?@ != 1 && @ != 2 && @ != 3 && @ != 4 && @ != 5 && @ != 6]`, `{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}`},
+		{`$.a[?@ < true]`, ``},
+		{`$.a[?@ == 1.0]`, `1`},
+		{`$.a[?@ == 1e0]`, `1`},
+		{`$.o[?@ < 3]`, `1,2`},
+		{`$.o[?@.u]`, `{"u":6}`},
+		{`$.e[?@.x == $.e[0].x]`, `{"x":[1,2]}`},
+		{`$.e[?@.x == $.e[2].x]`, `{"x":{"y":null}}`},
+		{`$.e[?@.x.y == null]`, `{"x":{"y":null}}`},
+		{`$..[?@.y == null]`, `{"y":null}`},
+		{`$[?@.p == 1]`, `{"p":1,"q":2,"r":3,"s":5,"t":{"u":6}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			path, err := jsonlite.CompileJSONPath(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var values []string
+			for _, v := range path.Values(doc) {
+				values = append(values, string(v.Compact(nil)))
+			}
+			if got := strings.Join(values, ","); got != tt.want {
+				t.Errorf("expected [%s], got [%s]", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONPathFunctions(t *testing.T) {
+	doc, err := jsonlite.Parse(`[
+		{"s": "abc", "a": [1, 2, 3], "o": {"x": 1}, "r": "a.c"},
+		{"s": "ab\nc", "a": [], "o": {}, "r": "b"},
+		{"s": "élan", "a": [1], "n": 4},
+		{"s": 42, "a": [[1], [2]]}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`$[?length(@.s) == 3]`, `0`},
+		{`$[?length(@.s) == 4]`, `1,2`},
+		{`$[?length(@.a) == 0]`, `1`},
+		{`$[?length(@.o) == 1]`, `0`},
+		{`$[?length(@.n) == 1]`, ``},
+		{`$[?length(@.missing) == 0]`, ``},
+		{`$[?count(@.a[*]) == 2]`, `3`},
+		{`$[?count(@.*) == 4]`, `0,1`},
+		{`$[?count(@..*) > 7]`, `0`},
+		{`$[?value(@.a[0]) == 1]`, `0,2`},
+		{`$[?value(@.a[*]) == 1]`, `2`},
+		{`$[?match(@.s, 'a.c')]`, `0`},
+		{`$[?match(@.s, 'a')]`, ``},
+		{`$[?search(@.s, 'b')]`, `0,1`},
+		{`$[?search(@.s, 'b.c')]`, ``},
+		{`$[?search(@.s, '^é')]`, ``},
+		{`$[?search(@.s, '[é]')]`, `2`},
+		{`$[?match(@.s, @.r)]`, `0`},
+		{`$[?search(@.s, @.r)]`, `0,1`},
+		{`$[?match(@.s, '(')]`, ``},
+		{`$[?!match(@.s, 'a.*')]`, `1,2,3`},
+		{`$[?match(@.s, 'a.*') == true]`, `error`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			path, err := jsonlite.CompileJSONPath(tt.query)
+			if tt.want == "error" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var indexes []string
+			for p := range path.Select(doc) {
+				indexes = append(indexes, strings.Trim(p, "$[]"))
+			}
+			if got := strings.Join(indexes, ","); got != tt.want {
+				t.Errorf("expected [%s], got [%s]", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONPathDescendants(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := selectJSONPath(t, doc, `$..[*]`)
+	want := strings.Join([]string{
+		`$['o'] {"j":1,"k":2}`,
+		`$['a'] [5,3,[{"j":4},{"k":6}]]`,
+		`$['o']['j'] 1`,
+		`$['o']['k'] 2`,
+		`$['a'][0] 5`,
+		`$['a'][1] 3`,
+		`$['a'][2] [{"j":4},{"k":6}]`,
+		`$['a'][2][0] {"j":4}`,
+		`$['a'][2][1] {"k":6}`,
+		`$['a'][2][0]['j'] 4`,
+		`$['a'][2][1]['k'] 6`,
+	}, "\n")
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	got = selectJSONPath(t, doc, `$..j`)
+	want = "$['o']['j'] 1\n$['a'][2][0]['j'] 4"
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestJSONPathNormalizedPaths(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"it's": 1, "back\\slash": 2, "line\nfeed": 3, "\u0001": 4, "日本": 5}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := selectJSONPath(t, doc, `$.*`)
+	want := strings.Join([]string{
+		`$['it\'s'] 1`,
+		`$['back\\slash'] 2`,
+		`$['line\nfeed'] 3`,
+		`$['\u0001'] 4`,
+		`$['日本'] 5`,
+	}, "\n")
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	got = selectJSONPath(t, doc, `$['it\'s', "back\\slash", 'line\nfeed', '\u0001', "日本"]`)
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	queries := []string{
+		``,
+		`store`,
+		` $`,
+		`$ `,
+		`$.`,
+		`$..`,
+		`$.1a`,
+		`$[`,
+		`$[]`,
+		`$[0`,
+		`$[01]`,
+		`$[-0]`,
+		`$[9007199254740992]`,
+		`$[1:2:3:4]`,
+		`$['a`,
+		`$['\"']`,
+		`$["\'"]`,
+		`$['\q']`,
+		`$[?]`,
+		`$[?1]`,
+		`$[?'a']`,
+		`$[?@.a == ]`,
+		`$[?@.* == 1]`,
+		`$[?@..a == 1]`,
+		`$[?@['a','b'] == 1]`,
+		`$[?(@.a]`,
+		`$[?@.a = 1]`,
+		`$[?length(@.a)]`,
+		`$[?length(@.*) == 1]`,
+		`$[?count(1) == 1]`,
+		`$[?count(@.a, @.b) == 1]`,
+		`$[?match(@.a) == 1]`,
+		`$[?unknown(@.a)]`,
+		`$[?!@.a == 1]`,
+		`$[?@.a == 1.]`,
+		`$[?@.a == nil]`,
+		`$[?@.a == {}]`,
+		`$[日本]`,
+	}
+
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			_, err := jsonlite.CompileJSONPath(query)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !errors.Is(err, jsonlite.ErrInvalidJSONPath) {
+				t.Errorf("expected error to wrap ErrInvalidJSONPath, got %v", err)
+			}
+			var e *jsonlite.JSONPathError
+			if !errors.As(err, &e) {
+				t.Fatalf("expected *JSONPathError, got %T", err)
+			}
+			if e.Query != query || e.Offset < 0 || e.Offset > len(query) {
+				t.Errorf("unexpected error location: %+v", e)
+			}
+		})
+	}
+}
+
+func TestJSONPathErrorOffset(t *testing.T) {
+	_, err := jsonlite.CompileJSONPath(`$.a[?@.b == 01]`)
+	var e *jsonlite.JSONPathError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *JSONPathError, got %v", err)
+	}
+	if e.Offset != 12 {
+		t.Errorf("expected offset 12, got %d (%v)", e.Offset, e)
+	}
+}
+
+func TestJSONPathStop(t *testing.T) {
+	doc, err := jsonlite.Parse(rfc9535Document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := jsonlite.MustCompileJSONPath(`$..*`)
+	n := 0
+	for range path.Select(doc) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("expected iteration to stop after 3 nodes, got %d", n)
+	}
+	if path.String() != `$..*` {
+		t.Errorf("expected query $..*, got %s", path.String())
+	}
+}
+
+func BenchmarkJSONPath(b *testing.B) {
+	doc, err := jsonlite.Parse(rfc9535Document)
+	if err != nil {
+		b.Fatal(err)
+	}
+	path := jsonlite.MustCompileJSONPath(`$..book[?@.price < 10 && match(@.category, 'f.*')].title`)
+
+	b.Run("Values", func(b *testing.B) {
+		for b.Loop() {
+			path.Values(doc)
+		}
+	})
+	b.Run("Select", func(b *testing.B) {
+		for b.Loop() {
+			for range path.Select(doc) {
+			}
+		}
+	})
+}
@@ -0,0 +1,535 @@
+package jsonlite
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	timeType          = reflect.TypeFor[time.Time]()
+)
+
+// Marshal returns the compact JSON encoding of v.
+//
+// Values are encoded following the rules of encoding/json, with the Append
+// helpers of this package: struct fields are named by their json tag or their
+// Go name, fields tagged "-" are omitted, fields with the ",omitempty" option
+// are omitted when they hold the zero value of a boolean, number, string or
+// pointer, or an empty array, slice or map, and fields with the ",string"
+// option hold their boolean, number or string value in a JSON string. The
+// fields of embedded structs are promoted.
+//
+// Types implementing json.Marshaler are encoded with MarshalJSON, whose output
+// is validated and compacted, and types implementing encoding.TextMarshaler as
+// a JSON string holding the result of MarshalText. Methods with a pointer
+// receiver are used when the value is addressable, like in encoding/json.
+// time.Time values are encoded with AppendTime, []byte with AppendBytes, and
+// map keys are sorted.
+//
+// The encoder of each Go type is built once and cached. A *MarshalError is
+// returned for values which cannot be encoded, such as channels, functions,
+// NaN or infinite floating-point numbers, and values nested deeper than
+// DefaultMaxNesting, which includes cyclic data structures since they could
+// not be parsed back.
+func Marshal(v any) ([]byte, error) { return AppendMarshal(nil, v) }
+
+// AppendMarshal appends the compact JSON encoding of v to buf, as described in
+// Marshal. On error, buf is returned unchanged.
+func AppendMarshal(buf []byte, v any) ([]byte, error) {
+	return encodeState{}.marshal(buf, v)
+}
+
+// MarshalIndent is like Marshal but indents the output like AppendIndentArray
+// and AppendIndentObject, using indent for the indentation of each level.
+func MarshalIndent(v any, indent IndentFunc) ([]byte, error) {
+	return AppendMarshalIndent(nil, v, 0, indent)
+}
+
+// AppendMarshalIndent is like AppendMarshal but indents the output like
+// AppendIndentArray and AppendIndentObject. The level parameter specifies the
+// nesting depth of v, and indent provides the indentation string for each
+// level.
+func AppendMarshalIndent(buf []byte, v any, level int, indent IndentFunc) ([]byte, error) {
+	return encodeState{indent: indent, level: level}.marshal(buf, v)
+}
+
+// encodeState holds the state of the encoding of a value. It is passed by
+// value, each array and object encoding its elements with a nested state.
+type encodeState struct {
+	indent IndentFunc // nil when the output is compact
+	level  int        // indentation level of the root value
+	depth  int        // nesting depth of the value being encoded
+}
+
+func (e encodeState) marshal(buf []byte, v any) ([]byte, error) {
+	if v == nil {
+		return AppendNull(buf), nil
+	}
+	rv := reflect.ValueOf(v)
+	b, err := encoderOf(rv.Type())(e, buf, rv)
+	if err != nil {
+		return buf, resolvePath(err)
+	}
+	return b, nil
+}
+
+// nest returns the state of the elements of an array or object of type t.
+func (e encodeState) nest(t reflect.Type) (encodeState, error) {
+	if e.depth >= DefaultMaxNesting {
+		return e, marshalError(t, fmt.Errorf("%w: %d", ErrDepthExceeded, DefaultMaxNesting))
+	}
+	e.depth++
+	return e, nil
+}
+
+// appendSeparator appends the separator of the i-th element of an array or
+// object, where e is the state of the elements.
+func (e encodeState) appendSeparator(b []byte, i int) []byte {
+	if i > 0 {
+		b = append(b, ',')
+	}
+	if e.indent != nil {
+		b = append(b, '\n')
+		b = append(b, e.indent(e.level+e.depth)...)
+	}
+	return b
+}
+
+// appendClose appends the closing bracket c of an array or object with n
+// elements, where e is the state of the array or object.
+func (e encodeState) appendClose(b []byte, n int, c byte) []byte {
+	if e.indent != nil && n > 0 {
+		b = append(b, '\n')
+		b = append(b, e.indent(e.level+e.depth)...)
+	}
+	return append(b, c)
+}
+
+// appendColon appends the colon after the key of an object field.
+func (e encodeState) appendColon(b []byte) []byte {
+	if e.indent != nil {
+		return append(b, ':', ' ')
+	}
+	return append(b, ':')
+}
+
+// appendJSON appends the JSON text s, returned by a MarshalJSON method,
+// validating and reformatting it.
+func (e encodeState) appendJSON(b []byte, s string) ([]byte, error) {
+	v, err := Parse(s)
+	if err != nil {
+		return b, err
+	}
+	if e.indent == nil {
+		return v.Compact(b), nil
+	}
+	return appendIndentValue(b, v, e.level+e.depth, e.indent), nil
+}
+
+// appendIndentValue appends the indented JSON text of v to b.
+func appendIndentValue(b []byte, v *Value, level int, indent IndentFunc) []byte {
+	switch v.Kind() {
+	case Array:
+		return AppendIndentArray(b, v.Array, func(b []byte, elem *Value) []byte {
+			return appendIndentValue(b, elem, level+1, indent)
+		}, level, indent)
+	case Object:
+		return AppendIndentObject(b, v.Object, func(b []byte, elem *Value) []byte {
+			return appendIndentValue(b, elem, level+1, indent)
+		}, level, indent)
+	default:
+		return append(b, v.json()...)
+	}
+}
+
+// marshalError returns the error for a Go value of type t which cannot be
+// encoded. The path of the error is completed by the encoders of the
+// enclosing containers.
+func marshalError(t reflect.Type, err error) error {
+	return &MarshalError{Type: t, Err: err, relativePath: relativePath{relative: true}}
+}
+
+// encoder appends the JSON encoding of v to b.
+type encoder func(e encodeState, b []byte, v reflect.Value) ([]byte, error)
+
+var encoders sync.Map // map[reflect.Type]encoder
+
+func encoderOf(t reflect.Type) encoder {
+	if enc, ok := encoders.Load(t); ok {
+		return enc.(encoder)
+	}
+	enc, _ := encoders.LoadOrStore(t, newEncoder(t, map[reflect.Type]*encoder{}))
+	return enc.(encoder)
+}
+
+// newEncoder builds the encoder of type t. The encoders being built are
+// recorded in seen, so recursive types refer to them indirectly.
+func newEncoder(t reflect.Type, seen map[reflect.Type]*encoder) encoder {
+	if enc, ok := seen[t]; ok {
+		return func(e encodeState, b []byte, v reflect.Value) ([]byte, error) { return (*enc)(e, b, v) }
+	}
+	if enc, ok := encoders.Load(t); ok {
+		return enc.(encoder)
+	}
+	enc := new(encoder)
+	seen[t] = enc
+
+	switch {
+	case t == timeType:
+		*enc = encodeTime
+	case t.Implements(jsonMarshalerType):
+		*enc = encodeJSONMarshaler
+	case t.Implements(textMarshalerType):
+		*enc = encodeTextMarshaler
+	case t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(jsonMarshalerType):
+		*enc = newAddrEncoder(encodeJSONMarshaler, newKindEncoder(t, seen))
+	case t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textMarshalerType):
+		*enc = newAddrEncoder(encodeTextMarshaler, newKindEncoder(t, seen))
+	default:
+		*enc = newKindEncoder(t, seen)
+	}
+	return *enc
+}
+
+// newKindEncoder builds the encoder of type t based on its kind, ignoring the
+// marshaling methods of t.
+func newKindEncoder(t reflect.Type, seen map[reflect.Type]*encoder) encoder {
+	if t == numberType {
+		return encodeNumber
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return encodeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return encodeInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return encodeUint
+	case reflect.Float32:
+		return encodeFloat32
+	case reflect.Float64:
+		return encodeFloat64
+	case reflect.String:
+		return encodeString
+	case reflect.Interface:
+		return encodeInterface
+	case reflect.Pointer:
+		return newPointerEncoder(newEncoder(t.Elem(), seen))
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !t.Elem().Implements(jsonMarshalerType) && !reflect.PointerTo(t.Elem()).Implements(jsonMarshalerType) {
+			return encodeBytes
+		}
+		return newSliceEncoder(newEncoder(t.Elem(), seen))
+	case reflect.Array:
+		return newArrayEncoder(newEncoder(t.Elem(), seen))
+	case reflect.Map:
+		return newMapEncoder(t, newEncoder(t.Elem(), seen))
+	case reflect.Struct:
+		return newStructEncoder(t, seen)
+	default:
+		return encodeUnsupported
+	}
+}
+
+// newAddrEncoder returns an encoder using the marshaling method of the pointer
+// to the value with addr when it is addressable, and fallback otherwise.
+func newAddrEncoder(addr, fallback encoder) encoder {
+	return func(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+		if v.CanAddr() {
+			return addr(e, b, v.Addr())
+		}
+		return fallback(e, b, v)
+	}
+}
+
+func encodeUnsupported(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	return b, marshalError(v.Type(), fmt.Errorf("%w: unsupported type", ErrUnsupportedValue))
+}
+
+func encodeJSONMarshaler(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	if isNil(v) {
+		return AppendNull(b), nil
+	}
+	data, err := v.Interface().(json.Marshaler).MarshalJSON()
+	if err != nil {
+		return b, marshalError(v.Type(), err)
+	}
+	b, err = e.appendJSON(b, string(data))
+	if err != nil {
+		return b, marshalError(v.Type(), fmt.Errorf("invalid output of MarshalJSON: %w", err))
+	}
+	return b, nil
+}
+
+func encodeTextMarshaler(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	if isNil(v) {
+		return AppendNull(b), nil
+	}
+	text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return b, marshalError(v.Type(), err)
+	}
+	return AppendQuote(b, string(text)), nil
+}
+
+func encodeTime(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	t := v.Interface().(time.Time)
+	if y := t.Year(); y < 0 || y > 9999 {
+		return b, marshalError(v.Type(), fmt.Errorf("%w: year %d outside of range [0,9999]", ErrUnsupportedValue, y))
+	}
+	return AppendTime(b, t), nil
+}
+
+func encodeNumber(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	s := v.String()
+	if s == "" {
+		s = "0"
+	}
+	if !validNumber(s) {
+		return b, marshalError(v.Type(), fmt.Errorf("%w: invalid number %q", ErrUnsupportedValue, s))
+	}
+	return append(b, s...), nil
+}
+
+func encodeBool(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	return AppendBool(b, v.Bool()), nil
+}
+
+func encodeInt(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	return AppendInt(b, v.Int()), nil
+}
+
+func encodeUint(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	return AppendUint(b, v.Uint()), nil
+}
+
+func encodeFloat32(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	f := v.Float()
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return b, marshalError(v.Type(), fmt.Errorf("%w: %v", ErrUnsupportedValue, f))
+	}
+	// Formatting with the precision of float32 keeps 0.1 from being encoded
+	// as 0.10000000149011612.
+	return strconv.AppendFloat(b, f, 'g', -1, 32), nil
+}
+
+func encodeFloat64(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	f := v.Float()
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return b, marshalError(v.Type(), fmt.Errorf("%w: %v", ErrUnsupportedValue, f))
+	}
+	return AppendFloat(b, f), nil
+}
+
+func encodeString(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	return AppendQuote(b, v.String()), nil
+}
+
+func encodeBytes(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	if v.IsNil() {
+		return AppendNull(b), nil
+	}
+	return AppendBytes(b, v.Bytes()), nil
+}
+
+func encodeInterface(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	if v.IsNil() {
+		return AppendNull(b), nil
+	}
+	elem := v.Elem()
+	return encoderOf(elem.Type())(e, b, elem)
+}
+
+func newPointerEncoder(elem encoder) encoder {
+	return func(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+		if v.IsNil() {
+			return AppendNull(b), nil
+		}
+		return elem(e, b, v.Elem())
+	}
+}
+
+func newSliceEncoder(elem encoder) encoder {
+	array := newArrayEncoder(elem)
+	return func(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+		if v.IsNil() {
+			return AppendNull(b), nil
+		}
+		return array(e, b, v)
+	}
+}
+
+func newArrayEncoder(elem encoder) encoder {
+	return func(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+		inner, err := e.nest(v.Type())
+		if err != nil {
+			return b, err
+		}
+		b = append(b, '[')
+		n := v.Len()
+		for i := range n {
+			b = inner.appendSeparator(b, i)
+			if b, err = elem(inner, b, v.Index(i)); err != nil {
+				return b, prependIndex(err, i)
+			}
+		}
+		return e.appendClose(b, n, ']'), nil
+	}
+}
+
+// mapEntry is an entry of a map being encoded, with its key converted to a
+// JSON object key.
+type mapEntry struct {
+	key   string
+	value reflect.Value
+}
+
+func newMapEncoder(t reflect.Type, elem encoder) encoder {
+	keyType := t.Key()
+	var mapKey func(k reflect.Value) (string, error)
+	switch {
+	case keyType.Kind() == reflect.String:
+		mapKey = func(k reflect.Value) (string, error) { return k.String(), nil }
+	case keyType.Implements(textMarshalerType):
+		mapKey = func(k reflect.Value) (string, error) {
+			if isNil(k) {
+				return "", nil
+			}
+			text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+			return string(text), err
+		}
+	default:
+		switch keyType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			mapKey = func(k reflect.Value) (string, error) { return strconv.FormatInt(k.Int(), 10), nil }
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			mapKey = func(k reflect.Value) (string, error) { return strconv.FormatUint(k.Uint(), 10), nil }
+		default:
+			return encodeUnsupported
+		}
+	}
+
+	return func(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+		if v.IsNil() {
+			return AppendNull(b), nil
+		}
+		inner, err := e.nest(t)
+		if err != nil {
+			return b, err
+		}
+		entries := make([]mapEntry, 0, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			key, err := mapKey(it.Key())
+			if err != nil {
+				return b, marshalError(keyType, err)
+			}
+			entries = append(entries, mapEntry{key: key, value: it.Value()})
+		}
+		slices.SortFunc(entries, func(a, b mapEntry) int { return cmp.Compare(a.key, b.key) })
+
+		b = append(b, '{')
+		for i := range entries {
+			entry := &entries[i]
+			b = inner.appendSeparator(b, i)
+			b = inner.appendColon(AppendQuote(b, entry.key))
+			if b, err = elem(inner, b, entry.value); err != nil {
+				return b, prependKey(err, entry.key)
+			}
+		}
+		return e.appendClose(b, len(entries), '}'), nil
+	}
+}
+
+func newStructEncoder(t reflect.Type, seen map[reflect.Type]*encoder) encoder {
+	fields := typeFields(t)
+	keys := make([]string, len(fields.list))
+	encoders := make([]encoder, len(fields.list))
+	for i, f := range fields.list {
+		keys[i] = Quote(f.name)
+		switch {
+		case f.quoted && f.typ.Kind() == reflect.Pointer:
+			encoders[i] = newPointerEncoder(newQuotedEncoder(newEncoder(f.typ.Elem(), seen)))
+		case f.quoted:
+			encoders[i] = newQuotedEncoder(newEncoder(f.typ, seen))
+		default:
+			encoders[i] = newEncoder(f.typ, seen)
+		}
+	}
+
+	return func(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+		inner, err := e.nest(t)
+		if err != nil {
+			return b, err
+		}
+		b = append(b, '{')
+		n := 0
+		for i := range fields.list {
+			f := &fields.list[i]
+			fv, ok := existingFieldByIndex(v, f.index)
+			if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+				continue
+			}
+			b = inner.appendSeparator(b, n)
+			b = inner.appendColon(append(b, keys[i]...))
+			if b, err = encoders[i](inner, b, fv); err != nil {
+				return b, prependKey(err, f.name)
+			}
+			n++
+		}
+		return e.appendClose(b, n, '}'), nil
+	}
+}
+
+// newQuotedEncoder returns the encoder of struct fields with the ",string"
+// option, which encodes their value with elem in a JSON string.
+func newQuotedEncoder(elem encoder) encoder {
+	return func(e encodeState, b []byte, v reflect.Value) ([]byte, error) {
+		start := len(b)
+		b, err := elem(e, b, v)
+		if err != nil {
+			return b, err
+		}
+		if v.Kind() == reflect.String {
+			return AppendQuote(b[:start], string(b[start:])), nil
+		}
+		b = append(b, '"')
+		copy(b[start+1:], b[start:len(b)-1])
+		b[start] = '"'
+		return append(b, '"'), nil
+	}
+}
+
+// isNil reports whether v is a nil pointer or interface.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// isEmptyValue reports whether v is empty for the ",omitempty" option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	default:
+		return false
+	}
+}
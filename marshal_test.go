@@ -0,0 +1,287 @@
+package jsonlite_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+type marshalPoint struct{ X, Y int }
+
+func (p marshalPoint) MarshalJSON() ([]byte, error) {
+	return []byte(`[ ` + jsonlite.Quote("x") + `, {"y" : 1} ]`), nil
+}
+
+type marshalCelsius float64
+
+func (c *marshalCelsius) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatFloat(float64(*c), 'f', -1, 64) + "C"), nil
+}
+
+type MarshalEmbedded struct {
+	Note   string `json:"note,omitempty"`
+	Shadow int
+}
+
+type marshalRecord struct {
+	*MarshalEmbedded
+	Shadow   string                `json:"Shadow"`
+	Name     string                `json:"name"`
+	Escaped  string                `json:"escaped"`
+	Tags     []string              `json:"tags,omitempty"`
+	NilTags  []string              `json:"nil_tags"`
+	Empty    map[string]int        `json:"empty,omitempty"`
+	Scores   map[string]float64    `json:"scores"`
+	ByID     map[int]bool          `json:"by_id"`
+	Addrs    map[netip.Addr]string `json:"addrs"`
+	Child    *marshalRecord        `json:"child,omitempty"`
+	Any      any                   `json:"any"`
+	Number   json.Number           `json:"number"`
+	Data     []byte                `json:"data"`
+	Pair     [2]int8               `json:"pair"`
+	When     time.Time             `json:"when"`
+	Point    marshalPoint          `json:"point"`
+	Temp     marshalCelsius        `json:"temp"`
+	ID       int64                 `json:"id,string"`
+	Label    string                `json:"label,string"`
+	Ratio    *float32              `json:"ratio,string"`
+	Enabled  bool                  `json:"enabled,omitempty"`
+	Count    uint16                `json:"count"`
+	Ignored  string                `json:"-"`
+	Dash     string                `json:"-,"`
+	Nullable *int                  `json:"nullable"`
+	private  int
+}
+
+func newMarshalRecord() *marshalRecord {
+	ratio := float32(0.1)
+	return &marshalRecord{
+		MarshalEmbedded: &MarshalEmbedded{Note: "embedded", Shadow: 1},
+		Shadow:          "outer",
+		Name:            "café",
+		Escaped:         "<a href=\"x\">\n\t </a>",
+		Scores:          map[string]float64{"z": 1.5, "a": -2, "m": 1e-7},
+		ByID:            map[int]bool{20: true, 3: false},
+		Addrs:           map[netip.Addr]string{netip.MustParseAddr("10.0.0.1"): "a"},
+		Child:           &marshalRecord{Name: "child", Pair: [2]int8{-1, 1}},
+		Any:             map[string]any{"list": []any{1, "s", true, nil, 2.5}},
+		Number:          "12.50",
+		Data:            []byte("hello"),
+		When:            time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC),
+		Point:           marshalPoint{X: 1},
+		Temp:            21.5,
+		ID:              -42,
+		Label:           "quoted",
+		Ratio:           &ratio,
+		Count:           65535,
+		Ignored:         "ignored",
+		Dash:            "dash",
+		private:         1,
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	v := newMarshalRecord()
+	want, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := jsonlite.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !jsonlite.Valid(string(got)) {
+		t.Fatalf("invalid JSON: %s", got)
+	}
+
+	// The outputs differ in the formatting of numbers and the escaping of
+	// HTML characters, so they are compared after decoding.
+	var wantAny, gotAny any
+	json.Unmarshal(want, &wantAny)
+	json.Unmarshal(got, &gotAny)
+	if !reflect.DeepEqual(gotAny, wantAny) {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	for _, s := range []string{
+		`"Shadow":"outer"`,
+		`"by_id":{"20":true,"3":false}`,
+		`"scores":{"a":-2,"m":1e-07,"z":1.5}`,
+		`"nil_tags":null`,
+		`"point":["x",{"y":1}]`,
+		`"id":"-42"`,
+		`"label":"\"quoted\""`,
+		`"ratio":"0.1"`,
+		`"temp":"21.5C"`,
+		`"-":"dash"`,
+		`"when":"2024-05-06T07:08:09.00000001Z"`,
+	} {
+		if !strings.Contains(string(got), s) {
+			t.Errorf("expected %s in %s", s, got)
+		}
+	}
+	if strings.Contains(string(got), `"tags"`) || strings.Contains(string(got), `"empty"`) || strings.Contains(string(got), `"enabled"`) {
+		t.Errorf("expected empty fields to be omitted in %s", got)
+	}
+}
+
+func TestMarshalAddressable(t *testing.T) {
+	// Methods with pointer receivers are only used on addressable values.
+	temp := marshalCelsius(1)
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{temp, `1`},
+		{&temp, `"1C"`},
+		{struct{ T marshalCelsius }{1}, `{"T":1}`},
+		{&struct{ T marshalCelsius }{1}, `{"T":"1C"}`},
+		{[]marshalCelsius{1}, `["1C"]`},
+		{(*marshalCelsius)(nil), `null`},
+		{(json.Marshaler)(nil), `null`},
+		{struct{ M json.Marshaler }{}, `{"M":null}`},
+		{nil, `null`},
+	}
+	for _, tt := range tests {
+		got, err := jsonlite.Marshal(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%#v: expected %s, got %s", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestMarshalIndent(t *testing.T) {
+	v := map[string]any{
+		"b":     []int{1, 2},
+		"a":     map[string]any{},
+		"empty": []string{},
+		"point": marshalPoint{},
+		"s":     struct{ X []int }{X: []int{3}},
+	}
+	want, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := jsonlite.MarshalIndent(v, jsonlite.Indent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	// The output matches the layout of AppendIndentObject at any level.
+	m := map[string][]int{"x": {1}}
+	got, err = jsonlite.AppendMarshalIndent([]byte("prefix"), m, 2, jsonlite.Indent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := jsonlite.AppendIndentObject([]byte("prefix"), func(yield func(string, []int) bool) { yield("x", m["x"]) },
+		func(b []byte, v []int) []byte {
+			return jsonlite.AppendIndentArray(b, func(yield func(int) bool) { yield(v[0]) }, func(b []byte, n int) []byte {
+				return jsonlite.AppendInt(b, int64(n))
+			}, 3, jsonlite.Indent)
+		}, 2, jsonlite.Indent)
+	if string(got) != string(expected) {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+type marshalFailing struct{}
+
+func (marshalFailing) MarshalJSON() ([]byte, error) { return nil, errors.New("failed") }
+
+type marshalInvalid struct{}
+
+func (marshalInvalid) MarshalJSON() ([]byte, error) { return []byte(`{"a":}`), nil }
+
+func TestMarshalErrors(t *testing.T) {
+	type node struct {
+		Next *node `json:"next"`
+	}
+	cycle := &node{}
+	cycle.Next = cycle
+
+	tests := []struct {
+		value any
+		err   error
+		path  string
+	}{
+		{map[string]any{"items": []any{1, math.NaN()}}, jsonlite.ErrUnsupportedValue, `$.items[1]`},
+		{struct{ F float32 }{float32(math.Inf(1))}, jsonlite.ErrUnsupportedValue, `$.F`},
+		{map[string]any{"a b": make(chan int)}, jsonlite.ErrUnsupportedValue, `$['a b']`},
+		{map[float64]int{1: 1}, jsonlite.ErrUnsupportedValue, `$`},
+		{struct{ N json.Number }{"1x"}, jsonlite.ErrUnsupportedValue, `$.N`},
+		{[]any{time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)}, jsonlite.ErrUnsupportedValue, `$[0]`},
+		{cycle, jsonlite.ErrDepthExceeded, `$` + strings.Repeat(`.next`, jsonlite.DefaultMaxNesting)},
+		{[]any{marshalFailing{}}, nil, `$[0]`},
+		{[]any{marshalInvalid{}}, jsonlite.ErrUnexpectedToken, `$[0]`},
+	}
+	for _, tt := range tests {
+		buf := []byte("prefix")
+		b, err := jsonlite.AppendMarshal(buf, tt.value)
+		var e *jsonlite.MarshalError
+		if !errors.As(err, &e) {
+			t.Fatalf("%T: expected marshal error, got %v", tt.value, err)
+		}
+		if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%T: expected %v, got %v", tt.value, tt.err, err)
+		}
+		if e.Path != tt.path {
+			t.Errorf("%T: expected error at %.40s, got %.40s", tt.value, tt.path, e.Path)
+		}
+		if string(b) != "prefix" {
+			t.Errorf("%T: expected buffer to be unchanged, got %.40s", tt.value, b)
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	type item struct {
+		ID    int64    `json:"id"`
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Price float64  `json:"price"`
+	}
+	type payload struct {
+		Items []item `json:"items"`
+		Total int    `json:"total"`
+	}
+	v := payload{Total: 100}
+	for i := range 100 {
+		v.Items = append(v.Items, item{ID: int64(i), Name: "some name", Tags: []string{"a", "b"}, Price: 9.99})
+	}
+
+	b.Run("AppendMarshal", func(b *testing.B) {
+		var buf []byte
+		for b.Loop() {
+			var err error
+			if buf, err = jsonlite.AppendMarshal(buf[:0], &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.SetBytes(int64(len(buf)))
+	})
+	b.Run("encoding/json", func(b *testing.B) {
+		var n int
+		for b.Loop() {
+			out, err := json.Marshal(&v)
+			if err != nil {
+				b.Fatal(err)
+			}
+			n = len(out)
+		}
+		b.SetBytes(int64(n))
+	})
+}
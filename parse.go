@@ -1,24 +1,21 @@
 package jsonlite
 
 import (
-	"errors"
-	"fmt"
 	"hash/maphash"
 	"iter"
+	"math"
 	"strings"
+	"sync"
 	"unsafe"
 )
 
 const (
 	// DefaultMaxDepth is the default maximum depth for parsing JSON objects.
 	DefaultMaxDepth = 100
-)
-
-var (
-	errEndOfObject           = errors.New("}")
-	errEndOfArray            = errors.New("]")
-	errUnexpectedEndOfObject = errors.New("unexpected end of object")
-	errUnexpectedEndOfArray  = errors.New("unexpected end of array")
+	// DefaultMaxNesting is the default limit on the number of nested arrays
+	// and objects in the input, beyond which parsing fails with
+	// ErrDepthExceeded.
+	DefaultMaxNesting = 10000
 )
 
 // whitespaceMap is a 256-bit lookup table for ASCII whitespace characters.
@@ -116,26 +113,86 @@ func nextToken(s string) (token, rest string, ok bool) {
 	}
 }
 
+// ParseOptions configures ParseWithOptions.
+//
+// The limits bound the work done when parsing untrusted input, parsing fails
+// as soon as one is exceeded with an error wrapping a *LimitError. A zero
+// value means no limit, except for MaxNesting and LazyDepth which then use
+// their defaults.
+type ParseOptions struct {
+	// MaxInputBytes is the maximum length of the input, checked before
+	// parsing.
+	MaxInputBytes int
+	// MaxStringLength is the maximum length of strings and object keys, in
+	// bytes of the input excluding the quotes.
+	MaxStringLength int
+	// MaxArrayElements is the maximum number of elements of each array.
+	MaxArrayElements int
+	// MaxObjectFields is the maximum number of fields of each object.
+	MaxObjectFields int
+	// MaxNodes is the maximum number of values in the input, counting
+	// arrays, objects and scalars but not object keys.
+	MaxNodes int
+	// MaxNesting is the maximum number of arrays and objects the input may
+	// be nested in. Defaults to DefaultMaxNesting; deeper input is rejected
+	// with an error which also wraps ErrDepthExceeded.
+	MaxNesting int
+	// LazyDepth is the number of nested objects which are parsed eagerly,
+	// arrays and objects nested deeper are stored unparsed and parsed when
+	// accessed. Only objects count towards the depth. Defaults to
+	// DefaultMaxDepth; a negative value stores the root value unparsed.
+	// Unparsed values are validated, and the limits enforced on them.
+	LazyDepth int
+}
+
+// ParseWithOptions parses JSON data with the given options and returns a
+// pointer to the root Value.
+// Returns an error if the JSON is malformed or empty, or exceeds the limits.
+func ParseWithOptions(data string, opts ParseOptions) (*Value, error) {
+	p := parsers.Get().(*parser)
+	defer parsers.Put(p)
+	p.configure(&opts)
+	return p.parse(data)
+}
+
 // ParseMaxDepth parses JSON data with a maximum nesting depth for objects.
-// Objects at maxDepth <= 0 are stored unparsed and will be lazily parsed
-// when accessed via Lookup(), Array(), or Object() methods.
+// Arrays and objects at maxDepth <= 0 are stored unparsed and will be lazily
+// parsed when accessed via Lookup(), Index(), Len(), Array(), or Object()
+// methods.
 // Depth is only decremented for objects, not arrays.
 // Returns an error if the JSON is malformed or empty.
+//
+// Deprecated: Use ParseWithOptions with ParseOptions.LazyDepth.
 func ParseMaxDepth(data string, maxDepth int) (*Value, error) {
-	v, rest, err := parseValue(data, max(0, maxDepth))
+	if maxDepth <= 0 {
+		maxDepth = -1
+	}
+	return ParseWithOptions(data, ParseOptions{LazyDepth: maxDepth})
+}
+
+// parse parses data which must contain a single JSON value.
+func (p *parser) parse(data string) (*Value, error) {
+	if len(data) > p.maxInputBytes {
+		return nil, &LimitError{Limit: "MaxInputBytes", Max: p.maxInputBytes}
+	}
+	v, rest, err := p.parseValue(data)
 	if err != nil {
-		return nil, err
+		p.reset()
+		return nil, locate(data, 0, err)
 	}
 	// Check for trailing content after the root value
-	if extra, _, ok := nextToken(rest); ok {
-		return nil, fmt.Errorf("unexpected token after root value: %q", extra)
+	if extra, next, ok := nextToken(rest); ok {
+		err := syntaxErrorf(extra, next, ErrTrailingData, "unexpected token after root value: %q", extra)
+		return nil, locate(data, len(data)-len(rest), err)
 	}
-	return &v, nil
+	root := p.arena.makeValues(1)
+	root[0] = v
+	return &root[0], nil
 }
 
 // Parse parses JSON data and returns a pointer to the root Value.
 // Returns an error if the JSON is malformed or empty.
-func Parse(data string) (*Value, error) { return ParseMaxDepth(data, DefaultMaxDepth) }
+func Parse(data string) (*Value, error) { return ParseWithOptions(data, ParseOptions{}) }
 
 // ParseSeq parses a sequence of JSON values from the input string.
 // It supports both JSON arrays (input starting with '[') and JSON Lines
@@ -159,11 +216,13 @@ func ParseSeq(json string) iter.Seq2[*Value, error] {
 			}
 			return
 		}
+		var p parser
+		p.configure(&ParseOptions{})
 		remaining := json
 		for {
-			v, rest, err := parseValue(remaining, DefaultMaxDepth)
+			v, rest, err := p.parseValue(remaining)
 			if err != nil {
-				yield(nil, err)
+				yield(nil, locate(json, len(json)-len(remaining), err))
 				return
 			}
 			if !yield(&v, nil) {
@@ -177,170 +236,592 @@ func ParseSeq(json string) iter.Seq2[*Value, error] {
 	}
 }
 
-// parseValue parses a JSON value from s.
-// Returns the parsed value, the remaining unparsed string, and any error.
-// The string is passed by value to keep it in registers.
-func parseValue(s string, maxDepth int) (Value, string, error) {
-	token, rest, ok := nextToken(s)
-	if !ok {
-		return Value{}, rest, errUnexpectedEndOfObject
-	}
-	switch token[0] {
-	case 'n':
-		if token != "null" {
-			return Value{}, rest, fmt.Errorf("invalid token: %q", token)
-		}
-		return makeNullValue(token[:4]), rest, nil
-	case 't':
-		if token != "true" {
-			return Value{}, rest, fmt.Errorf("invalid token: %q", token)
+// SeqStats counts the records of a sequence processed by ParseSeqRecover.
+type SeqStats struct {
+	// Accepted is the number of records parsed successfully.
+	Accepted int
+	// Skipped is the number of malformed records which were skipped.
+	Skipped int
+}
+
+// ParseSeqRecover is like ParseSeq, but keeps going after malformed records
+// of JSON Lines input. When a record fails to parse, the iterator yields a
+// *RecordError and resumes at the line following the error, or at the line of
+// the error when it starts the line, since the record was not terminated.
+// If stats is not nil, it is updated with the number of records accepted
+// and skipped during the iteration.
+//
+// Input starting with '[' is parsed as a single array like ParseSeq, and
+// cannot be recovered from errors.
+func ParseSeqRecover(json string, stats *SeqStats) iter.Seq2[*Value, error] {
+	return func(yield func(*Value, error) bool) {
+		if stats == nil {
+			stats = new(SeqStats)
 		}
-		return makeTrueValue(token[:4]), rest, nil
-	case 'f':
-		if token != "false" {
-			return Value{}, rest, fmt.Errorf("invalid token: %q", token)
+		token, _, ok := nextToken(json)
+		if !ok {
+			return
 		}
-		return makeFalseValue(token[:5]), rest, nil
-	case '"':
-		// Validate the quoted string but store the quoted token
-		if !validString(token) {
-			return Value{}, rest, fmt.Errorf("invalid token: %q", token)
+		if token == "[" {
+			for v, err := range ParseSeq(json) {
+				if err != nil {
+					stats.Skipped++
+				} else {
+					stats.Accepted++
+				}
+				if !yield(v, err) {
+					return
+				}
+			}
+			return
 		}
-		return makeStringValue(token), rest, nil
-	case '[':
-		return parseArray(s, rest, maxDepth)
-	case '{':
-		return parseObject(s, rest, maxDepth)
-	case ']':
-		return Value{}, rest, errEndOfArray
-	case '}':
-		return Value{}, rest, errEndOfObject
-	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		if !validNumber(token) {
-			return Value{}, rest, fmt.Errorf("invalid number: %q", token)
+		// Lines are counted lazily up to the start of records which fail to
+		// parse, so error-free input is not scanned for newlines.
+		line, lineStart, counted := 0, 0, 0
+		var p parser
+		p.configure(&ParseOptions{})
+		remaining := json
+		for {
+			v, rest, err := p.parseValue(remaining)
+			if err != nil {
+				start := len(json) - len(remaining)
+				for isWhitespace(json[start]) {
+					start++
+				}
+				if n := strings.Count(json[counted:start], "\n"); n > 0 {
+					line += n
+					lineStart = counted + strings.LastIndexByte(json[counted:start], '\n') + 1
+				}
+				counted = start
+				end := start
+				if e, ok := locate(json[start:], 0, err).(*SyntaxError); ok {
+					e.shift(start, line, start-lineStart)
+					end = e.Offset
+				}
+				// Skip to the end of the line where the error was found. If
+				// the error is at the start of a line, the record is not
+				// terminated and the line may hold the next one.
+				if i := strings.LastIndexByte(json[:end], '\n') + 1; i > start && strings.TrimLeft(json[i:end], " \t\r") == "" {
+					end = i - 1
+				} else if i := strings.IndexByte(json[end:], '\n'); i >= 0 {
+					end += i
+				} else {
+					end = len(json)
+				}
+				stats.Skipped++
+				if !yield(nil, &RecordError{Line: line + 1, Start: start, End: end, Err: err}) {
+					return
+				}
+				rest = json[end:]
+			} else {
+				stats.Accepted++
+				if !yield(&v, nil) {
+					return
+				}
+			}
+			remaining = rest
+			if _, _, ok := nextToken(remaining); !ok {
+				return
+			}
 		}
-		return makeNumberValue(token), rest, nil
-	default:
-		return Value{}, rest, fmt.Errorf("invalid token: %q", token)
 	}
 }
 
-func parseArray(start, json string, maxDepth int) (Value, string, error) {
-	elements := make([]Value, 0, 32)
+// parsers caches parsers so their scratch slices are reused across calls to
+// Parse.
+var parsers = sync.Pool{New: func() any { return new(parser) }}
+
+// parser is a JSON parser which uses an explicit stack instead of recursion,
+// so the goroutine stack does not grow with the nesting of the input.
+//
+// The elements of arrays and fields of objects being parsed are accumulated
+// in scratch slices shared by all levels of nesting, which are retained
+// between calls to parseValue when the parser is reused.
+type parser struct {
+	stack  []parseFrame
+	values []Value
+	fields []field
+	// objects is the number of objects in the stack, arrays and objects
+	// nested in maxDepth objects or more are stored unparsed.
+	objects       int
+	maxDepth      int
+	maxNesting    int
+	maxInputBytes int
+	// nodes is the number of values parsed so far, the limits are set to
+	// math.MaxInt when they are not configured.
+	nodes            int
+	maxNodes         int
+	maxStringLength  int
+	maxArrayElements int
+	maxObjectFields  int
+	limited          bool   // whether any of the limits above is configured
+	arena            *arena // storage of the parsed values, nil to allocate them
+	// projection selects the fields of objects which are parsed, nil to
+	// parse all of them. The next field holds the projection of the next
+	// value to parse, which is skipped when skipNext is set.
+	projection *projection
+	next       *projection
+	skipNext   bool
+}
+
+// parseFrame is an array or object being parsed.
+type parseFrame struct {
+	start  string // input starting with the opening bracket, to cache the JSON
+	key    string // key of the field being parsed
+	base   int    // index of the first element or field in the scratch slices
+	count  int    // number of commas, only tracked in objects stored unparsed
+	proj   *projection
+	object bool
+	// sparse is set when fields of the projection were skipped in the value
+	// or one of its children, so its JSON text is rebuilt from the tree.
+	sparse bool
+}
+
+// parseValue parses a JSON value from s.
+// Returns the parsed value, the remaining unparsed string, and any error.
+// The string is passed by value to keep it in registers.
+func (p *parser) parseValue(s string) (Value, string, error) {
+	p.reset()
 
-	for i := 0; ; i++ {
-		if i != 0 {
-			token, rest, ok := nextToken(json)
-			if !ok {
-				return Value{}, json, errUnexpectedEndOfArray
+	token, rest, ok := nextToken(s)
+	if !ok {
+		return Value{}, rest, syntaxErrorf("", "", ErrTruncated, "unexpected end of input")
+	}
+
+	p.next, p.skipNext = p.projection, false
+
+	for {
+		var v Value
+		var err error
+
+		skipped := p.skipNext
+		if skipped {
+			p.skipNext = false
+			if rest, err = p.skipValue(token, rest); err != nil {
+				return Value{}, rest, err
 			}
-			if token == "]" {
-				cached := start[:len(start)-len(rest)]
-				result := make([]Value, len(elements)+1)
-				result[0] = makeStringValue(cached)
-				copy(result[1:], elements)
-				return makeArrayValue(result), rest, nil
+		} else {
+			if p.nodes++; p.nodes > p.maxNodes {
+				return Value{}, rest, limitError(token, rest, "MaxNodes", p.maxNodes)
 			}
-			if token != "," {
-				return Value{}, json, fmt.Errorf("expected ',' or ']', got %q", token)
+			switch token[0] {
+			case '[':
+				if len(p.stack) >= p.maxNesting {
+					return Value{}, rest, depthError(token, rest, p.maxNesting)
+				}
+				if p.objects >= p.maxDepth {
+					v, rest, err = p.skip(s, rest, Array)
+					if err != nil {
+						return Value{}, rest, err
+					}
+					break
+				}
+				p.stack = append(p.stack, parseFrame{start: s, base: len(p.values), proj: p.next})
+				s = rest
+				if token, rest, ok = nextToken(s); !ok {
+					return Value{}, rest, truncatedError(Array)
+				}
+				if token != "]" {
+					continue
+				}
+				v = p.closeArray(rest)
+			case '{':
+				if len(p.stack) >= p.maxNesting {
+					return Value{}, rest, depthError(token, rest, p.maxNesting)
+				}
+				if p.objects >= p.maxDepth {
+					v, rest, err = p.skip(s, rest, Object)
+					if err != nil {
+						return Value{}, rest, err
+					}
+					break
+				}
+				p.stack = append(p.stack, parseFrame{start: s, base: len(p.fields), proj: p.next, object: true})
+				p.objects++
+				s = rest
+				if token, rest, ok = nextToken(s); !ok {
+					return Value{}, rest, truncatedError(Object)
+				}
+				if token != "}" {
+					if s, token, rest, err = p.parseKey(token, rest); err != nil {
+						return Value{}, rest, err
+					}
+					continue
+				}
+				v = p.closeObject(rest)
+			default:
+				if token[0] == '"' && len(token)-2 > p.maxStringLength {
+					return Value{}, rest, limitError(token, rest, "MaxStringLength", p.maxStringLength)
+				}
+				if v, err = parseScalar(token, rest); err != nil {
+					return Value{}, rest, err
+				}
 			}
-			json = rest
 		}
 
-		v, rest, err := parseValue(json, maxDepth)
-		if err != nil {
-			if i == 0 && err == errEndOfArray {
-				cached := start[:len(start)-len(rest)]
-				result := make([]Value, len(elements)+1)
-				result[0] = makeStringValue(cached)
-				copy(result[1:], elements)
-				return makeArrayValue(result), rest, nil
+		// Add the value to its parent, then close the parents which end
+		// after it until reaching one which has more elements.
+		for {
+			if len(p.stack) == 0 {
+				return v, rest, nil
+			}
+			top := &p.stack[len(p.stack)-1]
+			switch {
+			case skipped:
+				skipped = false
+				top.sparse = true
+			case top.object:
+				p.fields = append(p.fields, field{k: top.key, v: v})
+			default:
+				p.values = append(p.values, v)
 			}
-			if err == errEndOfArray {
-				return Value{}, json, fmt.Errorf("unexpected ']' after ','")
+
+			s = rest
+			if token, rest, ok = nextToken(s); !ok {
+				return Value{}, rest, truncatedError(top.kind())
+			}
+			switch {
+			case token == "]" && !top.object:
+				v = p.closeArray(rest)
+				continue
+			case token == "}" && top.object:
+				v = p.closeObject(rest)
+				continue
+			case token != ",":
+				if top.object {
+					return Value{}, rest, syntaxErrorf(token, rest, ErrUnexpectedToken, "expected ',' or '}', got %q", token)
+				}
+				return Value{}, rest, syntaxErrorf(token, rest, ErrUnexpectedToken, "expected ',' or ']', got %q", token)
 			}
-			return Value{}, json, err
+
+			s = rest
+			if token, rest, ok = nextToken(s); !ok {
+				return Value{}, rest, truncatedError(top.kind())
+			}
+			if top.object {
+				if len(p.fields)-top.base >= p.maxObjectFields {
+					return Value{}, rest, limitError(token, rest, "MaxObjectFields", p.maxObjectFields)
+				}
+				if s, token, rest, err = p.parseKey(token, rest); err != nil {
+					return Value{}, rest, err
+				}
+			} else if token == "]" {
+				return Value{}, rest, syntaxErrorf(token, rest, ErrUnexpectedToken, "unexpected ']' after ','")
+			} else if len(p.values)-top.base >= p.maxArrayElements {
+				return Value{}, rest, limitError(token, rest, "MaxArrayElements", p.maxArrayElements)
+			} else {
+				p.next = top.proj
+			}
+			break
 		}
-		json = rest
-		elements = append(elements, v)
 	}
 }
 
-func parseObject(start, json string, maxDepth int) (Value, string, error) {
-	if maxDepth == 0 {
-		depth, remain := 1, json
-		for depth > 0 {
-			token, next, ok := nextToken(remain)
-			if !ok {
-				return Value{}, remain, errUnexpectedEndOfObject
-			}
-			remain = next
-			switch token {
-			case "{":
-				depth++
-			case "}":
-				depth--
-			}
+// parseKey parses the object key in token, followed by rest in the input, and
+// the colon after it. It sets the key of the object at the top of the stack,
+// and returns the next token, the input it was read from, and the rest after
+// it.
+func (p *parser) parseKey(token, rest string) (s, next, after string, err error) {
+	if token[0] == '"' && len(token)-2 > p.maxStringLength {
+		return "", "", rest, limitError(token, rest, "MaxStringLength", p.maxStringLength)
+	}
+	key, err := Unquote(token)
+	if err != nil {
+		return "", "", rest, keyError(token, rest, err)
+	}
+	top := &p.stack[len(p.stack)-1]
+	top.key = key
+	p.next, p.skipNext = top.proj.child(key)
+
+	s = rest
+	token, rest, ok := nextToken(s)
+	if !ok {
+		return "", "", rest, truncatedError(Object)
+	}
+	if token != ":" {
+		return "", "", rest, syntaxErrorf(token, rest, ErrUnexpectedToken, "expected ':', got %q", token)
+	}
+
+	s = rest
+	token, rest, ok = nextToken(s)
+	if !ok {
+		return "", "", rest, syntaxErrorf("", "", ErrTruncated, "unexpected end of input")
+	}
+	return s, token, rest, nil
+}
+
+// closeArray pops the array at the top of the stack, which ends before rest,
+// and returns its value.
+func (p *parser) closeArray(rest string) Value {
+	top := &p.stack[len(p.stack)-1]
+	elements := p.values[top.base:]
+
+	cached := top.start[:len(top.start)-len(rest)]
+	if top.sparse {
+		cached = p.arrayJSON(elements)
+	}
+	result := p.arena.makeValues(len(elements) + 1)
+	result[0] = makeStringValue(cached)
+	copy(result[1:], elements)
+
+	// Clear the scratch space so pooled parsers do not retain the input.
+	clear(elements)
+	p.values = p.values[:top.base]
+	p.pop()
+	return makeArrayValue(result)
+}
+
+// closeObject pops the object at the top of the stack, which ends before
+// rest, and returns its value.
+func (p *parser) closeObject(rest string) Value {
+	top := &p.stack[len(p.stack)-1]
+	fields := p.fields[top.base:]
+
+	cached := top.start[:len(top.start)-len(rest)]
+	result := p.arena.makeFields(len(fields) + 1)
+	copy(result[1:], fields)
+
+	fields = result[1:]
+	hashes := p.arena.makeBytes(len(fields))
+	for i := range fields {
+		hashes[i] = byte(maphash.String(hashseed, fields[i].k))
+	}
+
+	if top.sparse {
+		cached = p.objectJSON(fields)
+	}
+	result[0].v = makeStringValue(cached)
+	result[0].k = unsafe.String(unsafe.SliceData(hashes), cap(hashes))
+
+	clear(p.fields[top.base:])
+	p.fields = p.fields[:top.base]
+	p.pop()
+	p.objects--
+	return makeObjectValue(result)
+}
+
+// arrayJSON returns the JSON text of an array of a projection, rebuilt from
+// its elements since fields of the projection were skipped in them.
+func (p *parser) arrayJSON(elements []Value) string {
+	n := 2 + len(elements)
+	for i := range elements {
+		n += len(elements[i].JSON())
+	}
+	b := append(p.arena.makeBytes(n)[:0], '[')
+	for i := range elements {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, elements[i].JSON()...)
+	}
+	b = append(b, ']')
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// objectJSON returns the JSON text of an object of a projection, rebuilt from
+// the fields which were not skipped.
+func (p *parser) objectJSON(fields []field) string {
+	n := 2 + len(fields)
+	for i := range fields {
+		n += len(fields[i].k) + 3 + len(fields[i].v.JSON())
+	}
+	b := append(p.arena.makeBytes(n)[:0], '{')
+	for i := range fields {
+		if i > 0 {
+			b = append(b, ',')
 		}
-		json := start[:len(start)-len(remain)]
-		return makeUnparsedObjectValue(json), remain, nil
+		b = AppendQuote(b, fields[i].k)
+		b = append(b, ':')
+		b = append(b, fields[i].v.JSON()...)
 	}
+	b = append(b, '}')
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
 
-	maxDepth--
-	fields := make([]field, 0, 16)
+// skip skips over the array or object starting in s, where rest is the input
+// after the opening bracket, and returns it as an unparsed value. The value is
+// validated, so parsing it lazily cannot fail.
+func (p *parser) skip(s, rest string, kind Kind) (Value, string, error) {
+	opening := "["
+	if kind == Object {
+		opening = "{"
+	}
+	tok := Tokenizer{json: rest}
+	if err := validTokens(tokenStream{tok: &tok}, opening, p.maxNesting-len(p.stack)); err != nil {
+		return Value{}, tok.json, err
+	}
+	remain := tok.json
+	if p.limited {
+		if next, err := p.skipLimited(rest, kind); err != nil {
+			return Value{}, next, err
+		}
+	}
+	return makeUnparsedValue(kind, p.arena.newLazy(s[:len(s)-len(remain)])), remain, nil
+}
 
-	for i := 0; ; i++ {
-		token, rest, ok := nextToken(json)
+// skipBrackets skips over the input until the closing bracket of an array or
+// object, where rest is the input after the opening bracket, and returns the
+// input after the closing bracket. Only the nesting of brackets is checked.
+func (p *parser) skipBrackets(rest string, kind Kind) (string, error) {
+	depth, remain := 1, rest
+	for depth > 0 {
+		token, next, ok := nextToken(remain)
 		if !ok {
-			return Value{}, json, errUnexpectedEndOfObject
+			return remain, truncatedError(kind)
 		}
-		if token == "}" {
-			cached := start[:len(start)-len(rest)]
-			result := make([]field, len(fields)+1)
-			copy(result[1:], fields)
-
-			fields := result[1:]
-			hashes := make([]byte, len(fields), (len(fields)*8+1)/8)
-			for i := range fields {
-				hashes[i] = byte(maphash.String(hashseed, fields[i].k))
+		switch token {
+		case "{", "[":
+			if len(p.stack)+depth >= p.maxNesting {
+				return next, depthError(token, next, p.maxNesting)
 			}
-
-			result[0].v = makeStringValue(cached)
-			result[0].k = unsafe.String(unsafe.SliceData(hashes), cap(hashes))
-			return makeObjectValue(result), rest, nil
+			depth++
+		case "}", "]":
+			depth--
 		}
-		json = rest
+		remain = next
+	}
+	return remain, nil
+}
+
+// skipValue skips over the value starting with token, followed by rest in the
+// input, and returns the input after it. Scalar values are validated, but the
+// content of arrays and objects is not.
+func (p *parser) skipValue(token, rest string) (string, error) {
+	switch token {
+	case "[":
+		return p.skipBrackets(rest, Array)
+	case "{":
+		return p.skipBrackets(rest, Object)
+	default:
+		_, err := parseScalar(token, rest)
+		return rest, err
+	}
+}
 
-		if i != 0 {
-			if token != "," {
-				return Value{}, json, fmt.Errorf("expected ',' or '}', got %q", token)
+// skipLimited enforces the limits of the parser on the array or object stored
+// unparsed by skip, where rest is the input after the opening bracket, so they
+// cannot be bypassed by parsing it lazily. The value must be valid.
+func (p *parser) skipLimited(rest string, kind Kind) (string, error) {
+	base := len(p.stack)
+	p.stack = append(p.stack, parseFrame{object: kind == Object})
+	key, remain := kind == Object, rest // whether the next string token is a key
+
+	for len(p.stack) > base {
+		token, next, _ := nextToken(remain)
+		top := &p.stack[len(p.stack)-1]
+
+		switch token {
+		case "}", "]":
+			p.pop()
+			key = false
+		case ",":
+			top.count++
+			switch {
+			case top.object && top.count >= p.maxObjectFields:
+				return next, limitError(token, next, "MaxObjectFields", p.maxObjectFields)
+			case !top.object && top.count >= p.maxArrayElements:
+				return next, limitError(token, next, "MaxArrayElements", p.maxArrayElements)
+			}
+			key = top.object
+		case ":":
+		default:
+			if token[0] == '"' && len(token)-2 > p.maxStringLength {
+				return next, limitError(token, next, "MaxStringLength", p.maxStringLength)
+			}
+			if key {
+				key = false
+				break
+			}
+			if p.nodes++; p.nodes > p.maxNodes {
+				return next, limitError(token, next, "MaxNodes", p.maxNodes)
 			}
-			token, rest, ok = nextToken(json)
-			if !ok {
-				return Value{}, json, errUnexpectedEndOfObject
+			if token == "{" || token == "[" {
+				p.stack = append(p.stack, parseFrame{object: token == "{"})
+				key = token == "{"
 			}
-			json = rest
 		}
+		remain = next
+	}
+	return remain, nil
+}
 
-		key, err := Unquote(token)
-		if err != nil {
-			return Value{}, json, fmt.Errorf("invalid key: %q: %w", token, err)
+// configure sets the limits of the parser from opts.
+func (p *parser) configure(opts *ParseOptions) {
+	limit := func(n int) int {
+		if n <= 0 {
+			return math.MaxInt
 		}
+		return n
+	}
+	p.maxDepth = DefaultMaxDepth
+	if opts.LazyDepth != 0 {
+		p.maxDepth = max(0, opts.LazyDepth)
+	}
+	p.maxNesting = DefaultMaxNesting
+	if opts.MaxNesting > 0 {
+		p.maxNesting = opts.MaxNesting
+	}
+	p.maxInputBytes = limit(opts.MaxInputBytes)
+	p.maxNodes = limit(opts.MaxNodes)
+	p.maxStringLength = limit(opts.MaxStringLength)
+	p.maxArrayElements = limit(opts.MaxArrayElements)
+	p.maxObjectFields = limit(opts.MaxObjectFields)
+	p.limited = opts.MaxNodes > 0 || opts.MaxStringLength > 0 || opts.MaxArrayElements > 0 || opts.MaxObjectFields > 0
+	p.projection = nil
+}
 
-		token, rest, ok = nextToken(json)
-		if !ok {
-			return Value{}, json, errUnexpectedEndOfObject
+// reset discards the state left by a previous call to parseValue, which may
+// have returned an error before the scratch slices were emptied.
+func (p *parser) reset() {
+	clear(p.stack)
+	clear(p.values)
+	clear(p.fields)
+	p.stack, p.values, p.fields, p.objects, p.nodes = p.stack[:0], p.values[:0], p.fields[:0], 0, 0
+}
+
+// pop removes the array or object at the top of the stack, marking its parent
+// as sparse when it is.
+func (p *parser) pop() {
+	sparse := p.stack[len(p.stack)-1].sparse
+	p.stack[len(p.stack)-1] = parseFrame{}
+	p.stack = p.stack[:len(p.stack)-1]
+	if sparse && len(p.stack) > 0 {
+		p.stack[len(p.stack)-1].sparse = true
+	}
+}
+
+func (f *parseFrame) kind() Kind {
+	if f.object {
+		return Object
+	}
+	return Array
+}
+
+// parseScalar parses the number, string or literal in token, followed by rest
+// in the input.
+func parseScalar(token, rest string) (Value, error) {
+	switch token[0] {
+	case 'n':
+		if token == "null" {
+			return makeNullValue(token), nil
 		}
-		if token != ":" {
-			return Value{}, json, fmt.Errorf("%q → expected ':', got %q", key, token)
+	case 't':
+		if token == "true" {
+			return makeTrueValue(token), nil
 		}
-		json = rest
-
-		val, rest, err := parseValue(json, maxDepth)
-		if err != nil {
-			return Value{}, json, fmt.Errorf("%q → %w", key, err)
+	case 'f':
+		if token == "false" {
+			return makeFalseValue(token), nil
+		}
+	case '"':
+		// Validate the quoted string but store the quoted token
+		if validString(token) {
+			return makeStringValue(token), nil
+		}
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		if validNumber(token) {
+			return makeNumberValue(token), nil
 		}
-		json = rest
-		fields = append(fields, field{k: key, v: val})
 	}
+	return Value{}, tokenError(token, rest)
 }
@@ -1,7 +1,9 @@
 package jsonlite_test
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/parquet-go/jsonlite"
@@ -201,6 +203,98 @@ func TestParseError(t *testing.T) {
 	}
 }
 
+func TestParseWithOptions(t *testing.T) {
+	const input = `{"id":1,"tags":["a","b","c"],"user":{"name":"gopher","langs":["go"]}}`
+
+	tests := []struct {
+		name  string
+		opts  jsonlite.ParseOptions
+		limit string
+	}{
+		{"no limits", jsonlite.ParseOptions{}, ""},
+		{"within limits", jsonlite.ParseOptions{MaxInputBytes: len(input), MaxStringLength: 6, MaxArrayElements: 3, MaxObjectFields: 3, MaxNodes: 10, MaxNesting: 3}, ""},
+		{"input bytes", jsonlite.ParseOptions{MaxInputBytes: len(input) - 1}, "MaxInputBytes"},
+		{"string length", jsonlite.ParseOptions{MaxStringLength: 5}, "MaxStringLength"},
+		{"key length", jsonlite.ParseOptions{MaxStringLength: 4}, "MaxStringLength"},
+		{"array elements", jsonlite.ParseOptions{MaxArrayElements: 2}, "MaxArrayElements"},
+		{"object fields", jsonlite.ParseOptions{MaxObjectFields: 2}, "MaxObjectFields"},
+		{"nodes", jsonlite.ParseOptions{MaxNodes: 9}, "MaxNodes"},
+		{"nesting", jsonlite.ParseOptions{MaxNesting: 2}, "MaxNesting"},
+		{"lazy string length", jsonlite.ParseOptions{LazyDepth: 1, MaxStringLength: 5}, "MaxStringLength"},
+		{"lazy array elements", jsonlite.ParseOptions{LazyDepth: -1, MaxArrayElements: 2}, "MaxArrayElements"},
+		{"lazy object fields", jsonlite.ParseOptions{LazyDepth: -1, MaxObjectFields: 2}, "MaxObjectFields"},
+		{"lazy nodes", jsonlite.ParseOptions{LazyDepth: 1, MaxNodes: 9}, "MaxNodes"},
+		{"lazy nesting", jsonlite.ParseOptions{LazyDepth: 1, MaxNesting: 2}, "MaxNesting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := jsonlite.ParseWithOptions(input, tt.opts)
+			if tt.limit == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v.JSON() != input {
+					t.Errorf("expected %s, got %s", input, v.JSON())
+				}
+				return
+			}
+			var limitErr *jsonlite.LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected *LimitError, got %T: %v", err, err)
+			}
+			if limitErr.Limit != tt.limit {
+				t.Errorf("expected %s to be exceeded, got %s", tt.limit, limitErr.Limit)
+			}
+			if !errors.Is(err, jsonlite.ErrLimitExceeded) {
+				t.Errorf("expected error to wrap ErrLimitExceeded: %v", err)
+			}
+			if tt.limit == "MaxNesting" && !errors.Is(err, jsonlite.ErrDepthExceeded) {
+				t.Errorf("expected error to wrap ErrDepthExceeded: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseWithOptionsLimitPosition(t *testing.T) {
+	_, err := jsonlite.ParseWithOptions(`{"a":[1,2,3,4]}`, jsonlite.ParseOptions{MaxArrayElements: 3})
+	checkSyntaxError(t, err, 12, 1, 13, "4", "$.a[3]")
+}
+
+func TestParseWithOptionsLazyDepth(t *testing.T) {
+	v, err := jsonlite.ParseWithOptions(`{"a":{"b":{"c":1}}}`, jsonlite.ParseOptions{LazyDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := v.Lookup("a").Lookup("b").Lookup("c"); c == nil || c.Int() != 1 {
+		t.Errorf("expected a.b.c to be 1, got %v", c)
+	}
+}
+
+func TestParseDeeplyNested(t *testing.T) {
+	for _, open := range []string{"[", `{"a":`, `[{"a":`} {
+		input := strings.Repeat(open, 1000000)
+		if _, err := jsonlite.Parse(input); !errors.Is(err, jsonlite.ErrDepthExceeded) {
+			t.Errorf("%q: expected ErrDepthExceeded, got %v", open, err)
+		}
+		if err := jsonlite.Validate(input); !errors.Is(err, jsonlite.ErrDepthExceeded) {
+			t.Errorf("%q: expected ErrDepthExceeded from Validate, got %v", open, err)
+		}
+	}
+
+	input := strings.Repeat("[", jsonlite.DefaultMaxNesting) + strings.Repeat("]", jsonlite.DefaultMaxNesting)
+	v, err := jsonlite.Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.JSON() != input {
+		t.Error("parsed value does not match the input")
+	}
+	if err := jsonlite.Validate(input); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestParseSeq(t *testing.T) {
 	t.Run("empty input", func(t *testing.T) {
 		count := 0
@@ -375,6 +469,118 @@ func TestParseSeq(t *testing.T) {
 	})
 }
 
+func TestParseSeqRecover(t *testing.T) {
+	t.Run("skips malformed lines", func(t *testing.T) {
+		input := "{\"a\":1}\n{\"a\":2,}\n{\"a\":3}\n  {\"a\":[4\n{\"a\":5}\n"
+		var stats jsonlite.SeqStats
+		var values []int64
+		var errs []*jsonlite.RecordError
+		for v, err := range jsonlite.ParseSeqRecover(input, &stats) {
+			if err != nil {
+				var recordErr *jsonlite.RecordError
+				if !errors.As(err, &recordErr) {
+					t.Fatalf("expected *RecordError, got %T: %v", err, err)
+				}
+				errs = append(errs, recordErr)
+				continue
+			}
+			values = append(values, v.Lookup("a").Int())
+		}
+		if fmt.Sprint(values) != "[1 3 5]" {
+			t.Errorf("expected values [1 3 5], got %v", values)
+		}
+		if stats.Accepted != 3 || stats.Skipped != 2 {
+			t.Errorf("expected 3 accepted and 2 skipped records, got %+v", stats)
+		}
+		if len(errs) != 2 {
+			t.Fatalf("expected 2 errors, got %d", len(errs))
+		}
+		if e := errs[0]; e.Line != 2 || e.Start != 8 || e.End != 16 {
+			t.Errorf("unexpected first error: %v", e)
+		}
+		if e := errs[1]; e.Line != 4 || e.Start != 27 || e.End != 34 {
+			t.Errorf("unexpected second error: %v", e)
+		}
+		var syntaxErr *jsonlite.SyntaxError
+		if !errors.As(errs[1], &syntaxErr) {
+			t.Fatalf("expected *SyntaxError, got %T", errs[1].Err)
+		}
+		if syntaxErr.Line != 5 || syntaxErr.Column != 1 || syntaxErr.Path != "$.a" {
+			t.Errorf("unexpected syntax error position: %v", syntaxErr)
+		}
+	})
+
+	t.Run("multi-line record", func(t *testing.T) {
+		// The record is skipped up to the end of the line of the error, not
+		// the line where it starts.
+		input := "{\"a\":1}\n{\"b\":\n 2,}\n{\"c\":3}\n"
+		var stats jsonlite.SeqStats
+		var keys []string
+		var errs []*jsonlite.RecordError
+		for v, err := range jsonlite.ParseSeqRecover(input, &stats) {
+			if err != nil {
+				var recordErr *jsonlite.RecordError
+				if !errors.As(err, &recordErr) {
+					t.Fatalf("expected *RecordError, got %T: %v", err, err)
+				}
+				errs = append(errs, recordErr)
+				continue
+			}
+			for k := range v.Object {
+				keys = append(keys, k)
+			}
+		}
+		if fmt.Sprint(keys) != "[a c]" {
+			t.Errorf("expected records [a c], got %v", keys)
+		}
+		if stats.Accepted != 2 || stats.Skipped != 1 || len(errs) != 1 {
+			t.Fatalf("expected 2 accepted and 1 skipped record, got %+v and %d errors", stats, len(errs))
+		}
+		if e := errs[0]; e.Line != 2 || e.Start != 8 || e.End != 18 || input[e.Start:e.End] != "{\"b\":\n 2,}" {
+			t.Errorf("unexpected error: %v", e)
+		}
+	})
+
+	t.Run("malformed last line", func(t *testing.T) {
+		var stats jsonlite.SeqStats
+		n := 0
+		for _, err := range jsonlite.ParseSeqRecover("1\n2\nnope", &stats) {
+			if err != nil {
+				if !errors.Is(err, jsonlite.ErrUnexpectedToken) {
+					t.Errorf("expected ErrUnexpectedToken, got %v", err)
+				}
+			}
+			n++
+		}
+		if n != 3 || stats.Accepted != 2 || stats.Skipped != 1 {
+			t.Errorf("expected 3 iterations with 2 accepted and 1 skipped, got %d and %+v", n, stats)
+		}
+	})
+
+	t.Run("early break", func(t *testing.T) {
+		count := 0
+		for range jsonlite.ParseSeqRecover("x\ny\nz", nil) {
+			count++
+			break
+		}
+		if count != 1 {
+			t.Errorf("expected 1 iteration before break, got %d", count)
+		}
+	})
+
+	t.Run("array input", func(t *testing.T) {
+		var stats jsonlite.SeqStats
+		for _, err := range jsonlite.ParseSeqRecover("[1, 2, 3]", &stats) {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if stats.Accepted != 3 || stats.Skipped != 0 {
+			t.Errorf("expected 3 accepted records, got %+v", stats)
+		}
+	})
+}
+
 func BenchmarkTokenize(b *testing.B) {
 	benchmarks := []struct {
 		name  string
@@ -694,6 +900,115 @@ func TestParseMaxDepth(t *testing.T) {
 	})
 }
 
+func TestParseLazyArrays(t *testing.T) {
+	const input = `{"series":{"name":"cpu","samples":[[1,0.5],[2,0.25],[3,{"v":1}]]}}`
+	opts := jsonlite.ParseOptions{LazyDepth: 1}
+
+	t.Run("index and len", func(t *testing.T) {
+		val, err := jsonlite.ParseWithOptions(input, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := val.Lookup("series").Lookup("samples")
+		if samples.Kind() != jsonlite.Array {
+			t.Fatalf("expected Array, got %v", samples.Kind())
+		}
+		if samples.Len() != 3 {
+			t.Fatalf("expected 3 samples, got %d", samples.Len())
+		}
+		if f := samples.Index(1).Index(1).Float(); f != 0.25 {
+			t.Errorf("expected 0.25, got %v", f)
+		}
+		if v := samples.Index(2).Index(1).Lookup("v"); v == nil || v.Int() != 1 {
+			t.Errorf("expected v=1, got %v", v)
+		}
+	})
+
+	t.Run("array iteration", func(t *testing.T) {
+		val, err := jsonlite.ParseWithOptions(`[[1,2],[3]]`, jsonlite.ParseOptions{LazyDepth: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum int64
+		for elem := range val.Array {
+			for x := range elem.Array {
+				sum += x.Int()
+			}
+		}
+		if sum != 6 {
+			t.Errorf("expected sum 6, got %d", sum)
+		}
+	})
+
+	t.Run("json and compact", func(t *testing.T) {
+		val, err := jsonlite.ParseWithOptions(`{"a":{"b":[1, 2, [3]]}}`, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := val.Lookup("a").Lookup("b")
+		if b.JSON() != `[1, 2, [3]]` {
+			t.Errorf("expected [1, 2, [3]], got %s", b.JSON())
+		}
+		if got := string(b.Compact(nil)); got != `[1,2,[3]]` {
+			t.Errorf("expected [1,2,[3]], got %s", got)
+		}
+		if got := string(val.Compact(nil)); got != `{"a":{"b":[1,2,[3]]}}` {
+			t.Errorf("expected compacted document, got %s", got)
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		lazy, err := jsonlite.ParseWithOptions(input, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eager, err := jsonlite.Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before := lazy.Size()
+		if before >= eager.Size() {
+			t.Errorf("expected unparsed arrays to use less memory: %d >= %d", before, eager.Size())
+		}
+		lazy.Lookup("series").Lookup("samples").Len()
+		if after := lazy.Size(); after <= before {
+			t.Errorf("expected Size() to include the parsed array: %d -> %d", before, after)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := jsonlite.ParseWithOptions(`{"a":{"b":[1,2`, opts)
+		if !errors.Is(err, jsonlite.ErrTruncated) {
+			t.Errorf("expected ErrTruncated, got %v", err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		// Values stored unparsed are validated, since parsing them lazily
+		// cannot report errors.
+		for _, input := range []string{
+			`{"a":[1,tru]}`,
+			`{"a":[1,2}}`,
+			`{"a":{"b" 1}}`,
+			`{"a":[1,,2]}`,
+			`{"a":[1,2,]}`,
+		} {
+			val, err := jsonlite.ParseMaxDepth(input, 1)
+			if !errors.Is(err, jsonlite.ErrUnexpectedToken) {
+				t.Errorf("%s: expected ErrUnexpectedToken, got %v", input, err)
+			}
+			if val != nil {
+				t.Errorf("%s: expected nil value, got %s", input, val.JSON())
+			}
+		}
+		_, err := jsonlite.ParseWithOptions(`{"a":[1,tru]}`, jsonlite.ParseOptions{LazyDepth: 1, MaxNodes: 10})
+		var e *jsonlite.SyntaxError
+		if !errors.As(err, &e) || e.Path != "$.a[1]" || e.Offset != 8 {
+			t.Errorf("expected syntax error at $.a[1] offset 8, got %v", err)
+		}
+	})
+}
+
 func TestLazyParsingCorrectness(t *testing.T) {
 	// Complex nested structure
 	input := `{
@@ -0,0 +1,124 @@
+package jsonlite
+
+const (
+	// arenaChunkSize is the minimum number of elements of the chunks
+	// allocated by the arena of a Parser.
+	arenaChunkSize = 1024
+)
+
+// Parser parses JSON documents into storage it owns, so the memory used by
+// the parsed values can be reused for the next documents after calling Reset.
+//
+// Parsing many small documents with a Parser avoids most of the allocations
+// done by Parse: once the storage has grown to fit the documents parsed
+// between calls to Reset, parsing them allocates no memory, except to unescape
+// object keys containing escape sequences.
+//
+// A Parser is not safe for concurrent use.
+type Parser struct {
+	parser parser
+	arena  arena
+}
+
+// NewParser returns a Parser which parses documents with the given options.
+func NewParser(opts ParseOptions) *Parser {
+	p := &Parser{}
+	p.parser.configure(&opts)
+	p.parser.arena = &p.arena
+	return p
+}
+
+// Parse parses JSON data and returns a pointer to the root Value.
+// Returns an error if the JSON is malformed or empty, or exceeds the limits
+// of the parser.
+//
+// The returned value is stored in memory owned by the parser, it remains
+// valid until the next call to Reset.
+func (p *Parser) Parse(data string) (*Value, error) {
+	return p.parser.parse(data)
+}
+
+// Reset reclaims the storage of all values returned by Parse, which must not
+// be used after Reset returns.
+func (p *Parser) Reset() {
+	p.parser.reset()
+	p.arena.reset()
+}
+
+// arena allocates the slices of values, fields and hash bytes making up the
+// parsed values, and the holders of objects stored unparsed. A nil arena allocates them from the heap.
+type arena struct {
+	values slab[Value]
+	fields slab[field]
+	bytes  slab[byte]
+	lazy   slab[lazyValue]
+}
+
+func (a *arena) makeValues(n int) []Value {
+	if a == nil {
+		return make([]Value, n)
+	}
+	return a.values.alloc(n)
+}
+
+func (a *arena) makeFields(n int) []field {
+	if a == nil {
+		return make([]field, n)
+	}
+	return a.fields.alloc(n)
+}
+
+func (a *arena) makeBytes(n int) []byte {
+	if a == nil {
+		return make([]byte, n)
+	}
+	return a.bytes.alloc(n)
+}
+
+func (a *arena) newLazy(json string) *lazyValue {
+	if a == nil {
+		return &lazyValue{json: json}
+	}
+	lazy := &a.lazy.alloc(1)[0]
+	lazy.json = json
+	return lazy
+}
+
+func (a *arena) reset() {
+	a.values.reset()
+	a.fields.reset()
+	a.bytes.reset()
+	a.lazy.reset()
+}
+
+// slab allocates slices from a list of chunks, which are retained and reused
+// from the first one after a reset.
+type slab[T any] struct {
+	chunks [][]T
+	index  int // index of the chunk slices are allocated from
+}
+
+// alloc returns a slice of n elements, which may contain values written
+// before the last reset.
+func (s *slab[T]) alloc(n int) []T {
+	for ; s.index < len(s.chunks); s.index++ {
+		c := s.chunks[s.index]
+		if i := len(c); n <= cap(c)-i {
+			s.chunks[s.index] = c[:i+n]
+			return c[i : i+n : i+n]
+		}
+	}
+	c := make([]T, n, max(n, arenaChunkSize))
+	s.chunks = append(s.chunks, c)
+	return c[:n:n]
+}
+
+// reset makes the memory of all chunks available again. The chunks are
+// cleared so they do not retain the inputs that the values referenced.
+func (s *slab[T]) reset() {
+	for i, c := range s.chunks[:min(s.index+1, len(s.chunks))] {
+		clear(c)
+		s.chunks[i] = c[:0]
+	}
+	s.index = 0
+}
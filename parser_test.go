@@ -0,0 +1,119 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestParser(t *testing.T) {
+	p := jsonlite.NewParser(jsonlite.ParseOptions{})
+
+	for round := range 3 {
+		var values []*jsonlite.Value
+		for i := range 100 {
+			v, err := p.Parse(fmt.Sprintf(`{"id":%d,"tags":["a","b"],"user":{"name":"user%d"}}`, i, i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, v)
+		}
+		// Values parsed since the last reset must all remain valid.
+		for i, v := range values {
+			if id := v.Lookup("id").Int(); id != int64(i) {
+				t.Fatalf("round %d: value %d: expected id %d, got %d", round, i, i, id)
+			}
+			if name := v.Lookup("user").Lookup("name").String(); name != fmt.Sprintf("user%d", i) {
+				t.Fatalf("round %d: value %d: unexpected name %q", round, i, name)
+			}
+			if n := v.Lookup("tags").Len(); n != 2 {
+				t.Fatalf("round %d: value %d: expected 2 tags, got %d", round, i, n)
+			}
+		}
+		p.Reset()
+	}
+}
+
+func TestParserLargeValues(t *testing.T) {
+	p := jsonlite.NewParser(jsonlite.ParseOptions{})
+	input := "[" + strings.Repeat(`{"a":1},`, 5000) + `{"a":1}]`
+	for range 2 {
+		v, err := p.Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := v.Len(); n != 5001 {
+			t.Fatalf("expected 5001 elements, got %d", n)
+		}
+		if v.JSON() != input {
+			t.Fatal("parsed value does not match the input")
+		}
+		p.Reset()
+	}
+}
+
+func TestParserOptions(t *testing.T) {
+	p := jsonlite.NewParser(jsonlite.ParseOptions{MaxArrayElements: 2})
+	if _, err := p.Parse(`[1,2]`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Parse(`[1,2,3]`); !errors.Is(err, jsonlite.ErrLimitExceeded) {
+		t.Errorf("expected ErrLimitExceeded, got %v", err)
+	}
+	// The parser remains usable after an error.
+	if _, err := p.Parse(`{"a":[1]}`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParserAllocs(t *testing.T) {
+	p := jsonlite.NewParser(jsonlite.ParseOptions{})
+	allocs := testing.AllocsPerRun(100, func() {
+		for range 10 {
+			if _, err := p.Parse(cloudLoggingPayload); err != nil {
+				t.Fatal(err)
+			}
+		}
+		p.Reset()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations in steady state, got %v", allocs)
+	}
+}
+
+func BenchmarkParser(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		input string
+	}{
+		{"Small", `{"name":"test","age":42,"active":true}`},
+		{"Nested", `{"id":1,"tags":["a","b","c"],"user":{"name":"Alice","roles":["admin","user"]}}`},
+		{"CloudLogging", cloudLoggingPayload},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name+"/Parse", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bm.input)))
+			for b.Loop() {
+				if _, err := jsonlite.Parse(bm.input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(bm.name+"/Parser", func(b *testing.B) {
+			p := jsonlite.NewParser(jsonlite.ParseOptions{})
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bm.input)))
+			for b.Loop() {
+				if _, err := p.Parse(bm.input); err != nil {
+					b.Fatal(err)
+				}
+				p.Reset()
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package jsonlite
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Partial is the result of ParsePartial.
+type Partial struct {
+	// Value is the best-effort value parsed from the input, nil if the input
+	// ended before any value could be kept.
+	Value *Value
+	// incomplete holds the values cut short by the end of the input, from the
+	// root to the innermost one, each being the last child of the previous.
+	incomplete []*Value
+}
+
+// Complete reports whether the input held a complete JSON value.
+func (p *Partial) Complete() bool { return p.Value != nil && len(p.incomplete) == 0 }
+
+// Incomplete reports whether v, a node of p.Value, was cut short by the end of
+// the input: an array or object left open, a string missing its closing quote,
+// or a number which may have more digits.
+//
+// Since the input is cut at a single point, the incomplete nodes are the root
+// and the chain of last children leading to the innermost value being parsed.
+// The nodes are compared by address, so v must have been obtained from
+// p.Value, for example with Index, Lookup or the Array and Object iterators.
+func (p *Partial) Incomplete(v *Value) bool { return slices.Contains(p.incomplete, v) }
+
+// ParsePartial parses a prefix of a JSON document, for example the output of
+// a language model as it is being streamed, and returns the best-effort value
+// it describes.
+//
+// Open strings, arrays and objects are closed. Partial escape sequences at the
+// end of strings are dropped, as well as keys with no value and literals which
+// are not fully typed, such as "tru"; numbers at the end of the input are kept
+// as long as their prefix is a valid number, "1." being read as 1.
+//
+// Only the end of the input is repaired: an error is returned if the input is
+// malformed before the point where it was cut, or has data after a complete
+// root value.
+func ParsePartial(data string) (*Partial, error) {
+	repaired, open, cut, err := repairPartial(data)
+	if err != nil {
+		return nil, locate(data, 0, err)
+	}
+	if repaired == "" {
+		return &Partial{}, nil
+	}
+	// The repaired input only differs from data after the point where it was
+	// cut, so the position of errors is the same in both.
+	root, err := Parse(repaired)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Partial{Value: root}
+	n := open
+	if cut {
+		n++
+	}
+	for i, v := 0, root; i < n; i++ {
+		if i > 0 {
+			v = lastChild(v)
+		}
+		p.incomplete = append(p.incomplete, v)
+	}
+	return p, nil
+}
+
+// Repair returns the compact JSON text of the value parsed from data by
+// ParsePartial, serialized with AppendArray and AppendObject. It returns an
+// empty string if data ended before any value could be kept.
+func Repair(data string) (string, error) {
+	p, err := ParsePartial(data)
+	if err != nil || p.Value == nil {
+		return "", err
+	}
+	return string(p.Value.Compact(nil)), nil
+}
+
+// States of repairPartial, describing the next token expected in the input.
+const (
+	partialValue = iota // a value, or ']' if first is set
+	partialKey          // an object key, or '}' if first is set
+	partialColon        // the colon after a key
+	partialNext         // a comma or the closing bracket of the innermost container
+	partialEnd          // nothing, the root value is complete
+)
+
+// repairPartial validates the prefix of a JSON document in data, and returns
+// it repaired into a complete JSON value, along with the number of arrays and
+// objects left open in the input and whether the innermost value was cut.
+//
+// The input is tokenized once, tracking the last point at which it can be
+// completed by appending the closing brackets of the open containers: after a
+// complete value, or after an opening bracket.
+func repairPartial(data string) (repaired string, open int, cut bool, err error) {
+	tok := Tokenize(data)
+	var stack []byte // closing brackets of the open containers
+	end, depth := 0, 0
+	state, first := partialValue, false
+
+	// after returns the state following a complete value, which is recorded
+	// as the last point at which the input can be completed.
+	after := func() int {
+		end, depth = len(data)-len(tok.json), len(stack)
+		if len(stack) == 0 {
+			return partialEnd
+		}
+		return partialNext
+	}
+	// pop closes the innermost container.
+	pop := func() int {
+		stack = stack[:len(stack)-1]
+		return after()
+	}
+
+scan:
+	for {
+		token, ok := tok.Next()
+		if !ok {
+			break
+		}
+		rest := tok.json
+
+		switch state {
+		case partialEnd:
+			return "", 0, false, syntaxErrorf(token, rest, ErrTrailingData, "unexpected token after root value: %q", token)
+
+		case partialKey:
+			if first && token == "}" {
+				state = pop()
+				break
+			}
+			if token[0] != '"' || !validString(token) {
+				if token[0] == '"' && rest == "" && truncatedToken(token) {
+					break scan // the key is dropped
+				}
+				return "", 0, false, keyError(token, rest, nil)
+			}
+			state = partialColon
+
+		case partialColon:
+			if token != ":" {
+				return "", 0, false, syntaxErrorf(token, rest, ErrUnexpectedToken, "expected ':', got %q", token)
+			}
+			state, first = partialValue, false
+
+		case partialNext:
+			closer := stack[len(stack)-1]
+			switch {
+			case token[0] == closer:
+				state = pop()
+			case token != ",":
+				return "", 0, false, syntaxErrorf(token, rest, ErrUnexpectedToken, "expected ',' or '%c', got %q", closer, token)
+			case closer == '}':
+				state, first = partialKey, false
+			default:
+				state, first = partialValue, false
+			}
+
+		case partialValue:
+			switch {
+			case token == "]" && first:
+				state = pop()
+			case token == "]" && len(stack) > 0:
+				// Trailing comma is not valid JSON
+				return "", 0, false, syntaxErrorf(token, rest, ErrUnexpectedToken, "unexpected ']' after ','")
+			case token[0] == '[' || token[0] == '{':
+				if len(stack) >= DefaultMaxNesting {
+					return "", 0, false, depthError(token, rest, DefaultMaxNesting)
+				}
+				state, first = partialValue, true
+				if token[0] == '{' {
+					stack, state = append(stack, '}'), partialKey
+				} else {
+					stack = append(stack, ']')
+				}
+				after()
+			default:
+				// Numbers at the end of the input may have more digits, even
+				// when they are valid.
+				err := validScalar(tok, token)
+				number := token[0] == '-' || (token[0] >= '0' && token[0] <= '9')
+				if rest == "" && (err != nil || number) && truncatedToken(token) {
+					if v, ok := repairScalar(token); ok {
+						start := len(data) - len(token)
+						return data[:start] + v + closers(stack), len(stack), true, nil
+					}
+					break scan // the literal is dropped
+				}
+				if err != nil {
+					return "", 0, false, err
+				}
+				state = after()
+			}
+		}
+	}
+
+	if end == 0 {
+		return "", 0, false, nil
+	}
+	return data[:end] + closers(stack[:depth]), depth, false, nil
+}
+
+// repairScalar returns the value repaired from token, which was cut short by
+// the end of the input, and whether it could be repaired. Strings are closed,
+// and numbers are truncated to their longest valid prefix.
+func repairScalar(token string) (string, bool) {
+	switch token[0] {
+	case '"':
+		content := trimPartialEscape(token[1:])
+		// Drop the last character if it is an incomplete UTF-8 sequence.
+		for i := len(content) - 1; i >= 0 && i >= len(content)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(content[i]) {
+				if !utf8.FullRuneInString(content[i:]) {
+					content = content[:i]
+				}
+				break
+			}
+		}
+		return `"` + content + `"`, true
+	case 'n', 't', 'f':
+		return "", false
+	default:
+		for token != "" && !validNumber(token) {
+			token = token[:len(token)-1]
+		}
+		return token, token != ""
+	}
+}
+
+// trimPartialEscape removes the escape sequence cut short at the end of the
+// content of a string, if any.
+func trimPartialEscape(content string) string {
+	i := strings.LastIndexByte(content, '\\')
+	if i < 0 || len(content)-i >= 6 {
+		return content
+	}
+	// Backslashes come in pairs when escaped: the sequence starts at the
+	// first one of an odd-length run.
+	n := 0
+	for j := i; j >= 0 && content[j] == '\\'; j-- {
+		n++
+	}
+	if n%2 == 0 {
+		return content
+	}
+	if esc := content[i+1:]; esc == "" || (esc[0] == 'u' && len(esc) < 5) {
+		return content[:i]
+	}
+	return content
+}
+
+// closers returns the closing brackets of the containers in stack, innermost
+// first.
+func closers(stack []byte) string {
+	b := make([]byte, len(stack))
+	for i, c := range stack {
+		b[len(b)-1-i] = c
+	}
+	return string(b)
+}
+
+// lastChild returns the last element of an array, or the value of the last
+// field of an object.
+func lastChild(v *Value) *Value {
+	if v.Kind() == Array {
+		return v.Index(v.Len() - 1)
+	}
+	var last *Value
+	for _, f := range v.Object {
+		last = f
+	}
+	return last
+}
@@ -0,0 +1,187 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{``, ``},
+		{`  `, ``},
+		{`{`, `{}`},
+		{`[`, `[]`},
+		{`{"a"`, `{}`},
+		{`{"a":`, `{}`},
+		{`{"a": 1, "b"`, `{"a":1}`},
+		{`{"a": 1, "b":`, `{"a":1}`},
+		{`{"a": 1,`, `{"a":1}`},
+		{`{"a": "hel`, `{"a":"hel"}`},
+		{`{"a": "hel\`, `{"a":"hel"}`},
+		{`{"a": "hel\\`, `{"a":"hel\\"}`},
+		{`{"a": "hel\"`, `{"a":"hel\""}`},
+		{`{"a": "caf\u00`, `{"a":"caf"}`},
+		{`{"a": "café`, `{"a":"café"}`},
+		{"{\"a\": \"caf\xc3", `{"a":"caf"}`},
+		{`"`, `""`},
+		{`[1, 2`, `[1,2]`},
+		{`[1, 2.`, `[1,2]`},
+		{`[1, 2.5e`, `[1,2.5]`},
+		{`[1, -`, `[1]`},
+		{`[true, fal`, `[true]`},
+		{`[true, false`, `[true,false]`},
+		{`[null, n`, `[null]`},
+		{`tru`, ``},
+		{`12`, `12`},
+		{`[[1, [2, {"x": [3`, `[[1,[2,{"x":[3]}]]]`},
+		{`[{"a": {}}, {"b": []`, `[{"a":{}},{"b":[]}]`},
+		{`{"a": [1, 2]}`, `{"a":[1,2]}`},
+		{`{"a": [1, 2]} `, `{"a":[1,2]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := jsonlite.Repair(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRepairPrefixes(t *testing.T) {
+	// Every prefix of a valid document must be repaired into valid JSON.
+	const input = `{"id": 123, "name": "café \"x\" \\", "ok": true, "none": null,
+		"items": [{"a": [-1.5e+10, 0, {}]}, [], "日本"], "nested": {"deep": [[{"z": false}]]}}`
+	for i := range len(input) + 1 {
+		repaired, err := jsonlite.Repair(input[:i])
+		if err != nil {
+			t.Fatalf("%q: %v", input[:i], err)
+		}
+		if repaired != "" && !jsonlite.Valid(repaired) {
+			t.Fatalf("%q: invalid repaired JSON %s", input[:i], repaired)
+		}
+	}
+	want, _ := jsonlite.Parse(input)
+	got, _ := jsonlite.Repair(input)
+	if got != string(want.Compact(nil)) {
+		t.Errorf("expected complete document to be unchanged, got %s", got)
+	}
+}
+
+func TestParsePartialIncomplete(t *testing.T) {
+	p, err := jsonlite.ParsePartial(`{"done": [1, 2], "msg": {"text": "hello wor`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Complete() {
+		t.Error("expected partial value")
+	}
+
+	root := p.Value
+	done := root.Lookup("done")
+	msg := root.Lookup("msg")
+	text := msg.Lookup("text")
+	if text.String() != "hello wor" {
+		t.Errorf("expected repaired string, got %q", text.String())
+	}
+	for _, v := range []*jsonlite.Value{root, msg, text} {
+		if !p.Incomplete(v) {
+			t.Errorf("expected %s to be incomplete", v.JSON())
+		}
+	}
+	for _, v := range []*jsonlite.Value{done, done.Index(0), done.Index(1)} {
+		if p.Incomplete(v) {
+			t.Errorf("expected %s to be complete", v.JSON())
+		}
+	}
+
+	// Dropping a dangling key leaves the object incomplete, but not the
+	// value of its last field.
+	p, err = jsonlite.ParsePartial(`{"a": [1], "b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Incomplete(p.Value) || p.Incomplete(p.Value.Lookup("a")) {
+		t.Error("expected only the root to be incomplete")
+	}
+
+	p, err = jsonlite.ParsePartial(`{"a": [1]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Complete() || p.Incomplete(p.Value) {
+		t.Error("expected complete value")
+	}
+
+	p, err = jsonlite.ParsePartial(`[1, tr`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Incomplete(p.Value) || p.Value.Len() != 1 || p.Incomplete(p.Value.Index(0)) {
+		t.Error("expected open array with one complete element")
+	}
+
+	p, err = jsonlite.ParsePartial(`nu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Value != nil || p.Complete() {
+		t.Error("expected no value")
+	}
+}
+
+func TestParsePartialErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		err    error
+		offset int
+	}{
+		{`[1 2`, jsonlite.ErrUnexpectedToken, 3},
+		{`{"a" 1`, jsonlite.ErrUnexpectedToken, 5},
+		{`{1: 2`, jsonlite.ErrUnexpectedToken, 1},
+		{`[1, ]`, jsonlite.ErrUnexpectedToken, 4},
+		{`[1, trux`, jsonlite.ErrUnexpectedToken, 4},
+		{`[01, 2`, jsonlite.ErrInvalidNumber, 1},
+		{`["\x", "a`, jsonlite.ErrInvalidEscape, 1},
+		{`{"a": "\q`, jsonlite.ErrInvalidEscape, 6},
+		{`{} {`, jsonlite.ErrTrailingData, 3},
+		{strings.Repeat(`[`, jsonlite.DefaultMaxNesting+1), jsonlite.ErrDepthExceeded, jsonlite.DefaultMaxNesting},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := jsonlite.ParsePartial(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			var e *jsonlite.SyntaxError
+			if !errors.As(err, &e) {
+				t.Fatalf("expected syntax error, got %T", err)
+			}
+			if e.Offset != tt.offset {
+				t.Errorf("expected error at offset %d, got %d", tt.offset, e.Offset)
+			}
+			if _, err := jsonlite.Repair(tt.input); err == nil {
+				t.Error("expected Repair to fail")
+			}
+		})
+	}
+}
+
+func BenchmarkParsePartial(b *testing.B) {
+	input := `{"id": 1, "items": [` + strings.Repeat(`{"k": [1, 2, 3, "abc"], "m": {"n": null}}, `, 100) + `{"k": "trunc`
+	b.SetBytes(int64(len(input)))
+	for b.Loop() {
+		jsonlite.ParsePartial(input)
+	}
+}
@@ -0,0 +1,131 @@
+package jsonlite
+
+import (
+	"hash/maphash"
+	"strings"
+	"unsafe"
+)
+
+// Path is a precompiled sequence of object keys and array indexes, which can
+// be looked up in many values without hashing the keys again.
+//
+// The zero value is the empty path, which selects the value itself.
+type Path struct {
+	segments []pathSegment
+}
+
+// pathSegment is a segment of a Path, which selects the field with the given
+// key in objects, and the element at index in arrays if it is not negative.
+type pathSegment struct {
+	key   string
+	hash  byte
+	index int
+}
+
+// CompilePath returns the Path made of the given segments. Each segment is an
+// object key, and also selects the element at that index in arrays when it is
+// a decimal number without leading zeros.
+func CompilePath(segments ...string) Path {
+	path := Path{segments: make([]pathSegment, len(segments))}
+	for i, s := range segments {
+		index, ok := pointerIndex(s)
+		if !ok {
+			index = -1
+		}
+		path.segments[i] = pathSegment{key: s, hash: byte(maphash.String(hashseed, s)), index: index}
+	}
+	return path
+}
+
+// Segments returns the segments of the path.
+func (p Path) Segments() []string {
+	segments := make([]string, len(p.segments))
+	for i := range p.segments {
+		segments[i] = p.segments[i].key
+	}
+	return segments
+}
+
+// String returns the segments of the path joined with dots.
+func (p Path) String() string { return strings.Join(p.Segments(), ".") }
+
+// Lookup returns the value at the path in v. Unlike LookupPath, it returns nil
+// instead of panicking when a segment is applied to a value which is neither
+// an object nor an array, or if v is nil.
+func (p Path) Lookup(v *Value) *Value {
+	for i := range p.segments {
+		if v == nil {
+			return nil
+		}
+		s := &p.segments[i]
+		switch v.Kind() {
+		case Object:
+			v = v.lookup(s.key, s.hash)
+		case Array:
+			if s.index < 0 || s.index >= v.Len() {
+				return nil
+			}
+			v = v.Index(s.index)
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// Keys is a precompiled set of object keys for LookupMany.
+type Keys struct {
+	keys   []string
+	hashes []byte
+	set    [4]uint64 // bitset of the hashes
+}
+
+// CompileKeys returns the Keys made of the given object keys.
+func CompileKeys(keys ...string) Keys {
+	k := Keys{keys: append([]string(nil), keys...), hashes: make([]byte, len(keys))}
+	for i, key := range keys {
+		h := byte(maphash.String(hashseed, key))
+		k.hashes[i] = h
+		k.set[h>>6] |= 1 << (h & 63)
+	}
+	return k
+}
+
+// Len returns the number of keys.
+func (k Keys) Len() int { return len(k.keys) }
+
+// LookupMany looks up all the keys in the object v in a single pass over its
+// fields, setting values[i] to the value of the i-th key, or to nil if the key
+// is not found. Like Lookup, the first field is selected when keys are
+// duplicated in the object.
+//
+// Panics if the value is not an object or if values is shorter than keys.
+func (v *Value) LookupMany(keys Keys, values []*Value) {
+	if v.Kind() != Object {
+		panic("jsonlite: LookupMany called on non-object value")
+	}
+	values = values[:len(keys.keys)]
+	clear(values)
+
+	parsed := v
+	if v.unparsed() {
+		parsed = v.parse()
+	}
+	fields := unsafe.Slice((*field)(parsed.p), parsed.len())
+	hashes := fields[0].k
+	missing := len(keys.keys)
+
+	for i := 0; i < len(hashes) && missing > 0; i++ {
+		h := hashes[i]
+		if keys.set[h>>6]&(1<<(h&63)) == 0 {
+			continue
+		}
+		f := &fields[i+1]
+		for j, kh := range keys.hashes {
+			if kh == h && values[j] == nil && keys.keys[j] == f.k {
+				values[j] = &f.v
+				missing--
+			}
+		}
+	}
+}
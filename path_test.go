@@ -0,0 +1,167 @@
+package jsonlite_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestPath(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"a": {"b": [10, {"c": "x"}], "0": "zero"}, "s": "str", "d": 1, "d": 2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{nil, string(doc.Compact(nil))},
+		{[]string{"a", "b", "0"}, `10`},
+		{[]string{"a", "b", "1", "c"}, `"x"`},
+		{[]string{"a", "0"}, `"zero"`},
+		{[]string{"a", "b", "2"}, ``},
+		{[]string{"a", "b", "01"}, ``},
+		{[]string{"a", "b", "-1"}, ``},
+		{[]string{"a", "b", "x"}, ``},
+		{[]string{"s", "x"}, ``},
+		{[]string{"missing", "x"}, ``},
+		{[]string{"d"}, `1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.path, "."), func(t *testing.T) {
+			p := jsonlite.CompilePath(tt.path...)
+			got := ""
+			if v := p.Lookup(doc); v != nil {
+				got = string(v.Compact(nil))
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if p.String() != strings.Join(tt.path, ".") {
+				t.Errorf("unexpected path string %q", p.String())
+			}
+		})
+	}
+
+	if v := jsonlite.CompilePath("a").Lookup(nil); v != nil {
+		t.Errorf("expected nil for nil value, got %v", v)
+	}
+}
+
+func TestPathLazy(t *testing.T) {
+	doc, err := jsonlite.ParseWithOptions(`{"a": {"b": [1, {"c": true}]}}`, jsonlite.ParseOptions{LazyDepth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := jsonlite.CompilePath("a", "b", "1", "c").Lookup(doc)
+	if v == nil || v.Kind() != jsonlite.True {
+		t.Errorf("expected true, got %v", v)
+	}
+}
+
+func TestLookupMany(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"id": 1, "name": "a", "tags": [], "id": 2, "extra": null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := jsonlite.CompileKeys("name", "missing", "id", "extra", "name")
+	values := make([]*jsonlite.Value, keys.Len())
+	doc.LookupMany(keys, values)
+
+	want := []string{`"a"`, ``, `1`, `null`, `"a"`}
+	for i, v := range values {
+		got := ""
+		if v != nil {
+			got = string(v.Compact(nil))
+		}
+		if got != want[i] {
+			t.Errorf("values[%d]: expected %s, got %s", i, want[i], got)
+		}
+	}
+}
+
+func TestLookupManyMatchesLookup(t *testing.T) {
+	// Enough fields for hash bytes to collide.
+	var b strings.Builder
+	b.WriteString("{")
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = fmt.Sprintf("field%d", i)
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "%q:%d", names[i], i)
+	}
+	b.WriteString("}")
+	doc, err := jsonlite.Parse(b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := jsonlite.CompileKeys(append(names, "unknown")...)
+	values := make([]*jsonlite.Value, keys.Len())
+	doc.LookupMany(keys, values)
+	for i, v := range values[:len(names)] {
+		if v != doc.Lookup(names[i]) {
+			t.Fatalf("%s: LookupMany and Lookup returned different values", names[i])
+		}
+	}
+	if values[len(names)] != nil {
+		t.Error("expected nil for unknown key")
+	}
+}
+
+func TestPathAllocs(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"a": {"b": [1, {"c": "x"}]}, "d": 1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := jsonlite.CompilePath("a", "b", "1", "c")
+	keys := jsonlite.CompileKeys("a", "d")
+	values := make([]*jsonlite.Value, keys.Len())
+
+	allocs := testing.AllocsPerRun(100, func() {
+		path.Lookup(doc)
+		doc.LookupMany(keys, values)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkPath(b *testing.B) {
+	doc, err := jsonlite.Parse(`{"user": {"profile": {"name": "a", "email": "b", "age": 3}}, "id": 4, "ts": 5}`)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("LookupPath", func(b *testing.B) {
+		for b.Loop() {
+			doc.LookupPath("user", "profile", "email")
+		}
+	})
+	b.Run("Path", func(b *testing.B) {
+		path := jsonlite.CompilePath("user", "profile", "email")
+		for b.Loop() {
+			path.Lookup(doc)
+		}
+	})
+	b.Run("Lookup", func(b *testing.B) {
+		for b.Loop() {
+			doc.Lookup("user")
+			doc.Lookup("id")
+			doc.Lookup("ts")
+		}
+	})
+	b.Run("LookupMany", func(b *testing.B) {
+		keys := jsonlite.CompileKeys("user", "id", "ts")
+		values := make([]*jsonlite.Value, keys.Len())
+		for b.Loop() {
+			doc.LookupMany(keys, values)
+		}
+	})
+}
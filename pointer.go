@@ -0,0 +1,173 @@
+package jsonlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pointer is a precompiled JSON Pointer (RFC 6901), which can be resolved
+// against many documents without parsing it again.
+//
+// The zero value is the empty pointer, which refers to the whole document.
+type Pointer struct {
+	tokens []string
+}
+
+// ParsePointer parses a JSON Pointer such as "/items/0/name". The escape
+// sequences ~0 and ~1 in reference tokens are decoded to '~' and '/'.
+// Returns an error wrapping ErrInvalidPointer if s is not a valid pointer.
+func ParsePointer(s string) (Pointer, error) {
+	if err := validPointer(s); err != nil {
+		return Pointer{}, err
+	}
+	var tokens []string
+	for rest := s; rest != ""; {
+		var token string
+		token, rest = nextPointerToken(rest)
+		tokens = append(tokens, unescapePointerToken(token))
+	}
+	return Pointer{tokens: tokens}, nil
+}
+
+// NewPointer returns a Pointer made of the given reference tokens, which are
+// unescaped object keys or array indexes.
+func NewPointer(tokens ...string) Pointer {
+	return Pointer{tokens: append([]string(nil), tokens...)}
+}
+
+// Tokens returns the unescaped reference tokens of the pointer.
+func (p Pointer) Tokens() []string { return append([]string(nil), p.tokens...) }
+
+// String returns the textual representation of the pointer, with '~' and '/'
+// escaped in reference tokens.
+func (p Pointer) String() string {
+	var b strings.Builder
+	for _, token := range p.tokens {
+		b.WriteByte('/')
+		for i := 0; i < len(token); i++ {
+			switch c := token[i]; c {
+			case '~':
+				b.WriteString("~0")
+			case '/':
+				b.WriteString("~1")
+			default:
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Resolve returns the value that the pointer refers to in v, resolving
+// reference tokens as object keys or array indexes depending on the kind of
+// the values they are applied to.
+//
+// Returns an error wrapping ErrPointerNotFound if a key is missing, an index
+// is out of range, or a token is applied to a value which is neither an array
+// nor an object.
+func (p Pointer) Resolve(v *Value) (*Value, error) {
+	for i, token := range p.tokens {
+		next, err := resolvePointerToken(v, token)
+		if err != nil {
+			return nil, &PointerError{Pointer: p.String(), Token: i, Err: err}
+		}
+		v = next
+	}
+	return v, nil
+}
+
+// Pointer returns the value that the JSON Pointer ptr refers to, such as
+// "/items/0/name". It is equivalent to parsing the pointer with ParsePointer
+// and resolving it, but avoids allocating memory unless reference tokens
+// contain escape sequences.
+//
+// Returns an error wrapping ErrInvalidPointer if ptr is invalid, or
+// ErrPointerNotFound if it does not match the shape of the document.
+func (v *Value) Pointer(ptr string) (*Value, error) {
+	if err := validPointer(ptr); err != nil {
+		return nil, err
+	}
+	for i, rest := 0, ptr; rest != ""; i++ {
+		var token string
+		token, rest = nextPointerToken(rest)
+		next, err := resolvePointerToken(v, unescapePointerToken(token))
+		if err != nil {
+			return nil, &PointerError{Pointer: ptr, Token: i, Err: err}
+		}
+		v = next
+	}
+	return v, nil
+}
+
+// resolvePointerToken resolves the unescaped reference token in v.
+func resolvePointerToken(v *Value, token string) (*Value, error) {
+	switch v.Kind() {
+	case Object:
+		if elem := v.Lookup(token); elem != nil {
+			return elem, nil
+		}
+		return nil, fmt.Errorf("%w: missing key %q", ErrPointerNotFound, token)
+	case Array:
+		i, ok := pointerIndex(token)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid array index %q", ErrPointerNotFound, token)
+		}
+		if n := v.Len(); i >= n {
+			return nil, fmt.Errorf("%w: index %s out of range for array of length %d", ErrPointerNotFound, token, n)
+		}
+		return v.Index(i), nil
+	default:
+		return nil, fmt.Errorf("%w: cannot resolve %q in %s value", ErrPointerNotFound, token, kindName(v.Kind()))
+	}
+}
+
+// pointerIndex parses an array index, which must not have leading zeros. The
+// "-" token, referring to the element after the last one, never resolves.
+func pointerIndex(token string) (int, bool) {
+	if token == "" || (token[0] == '0' && len(token) > 1) {
+		return 0, false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return 0, false
+		}
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, false // out of range of int
+	}
+	return i, true
+}
+
+// validPointer checks the syntax of a JSON Pointer.
+func validPointer(s string) error {
+	if s != "" && s[0] != '/' {
+		return &PointerError{Pointer: s, Token: -1, Err: fmt.Errorf("%w: must start with '/'", ErrInvalidPointer)}
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '~' && (i+1 == len(s) || (s[i+1] != '0' && s[i+1] != '1')) {
+			return &PointerError{Pointer: s, Token: -1, Err: fmt.Errorf("%w: '~' must be followed by '0' or '1'", ErrInvalidPointer)}
+		}
+	}
+	return nil
+}
+
+// nextPointerToken splits the first reference token of s, which must start
+// with '/', from the rest of the pointer.
+func nextPointerToken(s string) (token, rest string) {
+	s = s[1:]
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// unescapePointerToken decodes ~1 to '/' and ~0 to '~', in this order so
+// that "~01" becomes "~1".
+func unescapePointerToken(token string) string {
+	if strings.IndexByte(token, '~') < 0 {
+		return token
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
@@ -0,0 +1,188 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+// rfc6901Document is the example document of RFC 6901, section 5.
+const rfc6901Document = `{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8
+}`
+
+func TestPointer(t *testing.T) {
+	doc, err := jsonlite.Parse(rfc6901Document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pointer string
+		want    string
+	}{
+		{``, string(doc.Compact(nil))},
+		{`/foo`, `["bar","baz"]`},
+		{`/foo/0`, `"bar"`},
+		{`/foo/1`, `"baz"`},
+		{`/`, `0`},
+		{`/a~1b`, `1`},
+		{`/c%d`, `2`},
+		{`/e^f`, `3`},
+		{`/g|h`, `4`},
+		{`/i\j`, `5`},
+		{`/k"l`, `6`},
+		{`/ `, `7`},
+		{`/m~0n`, `8`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			v, err := doc.Pointer(tt.pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(v.Compact(nil)); got != tt.want {
+				t.Errorf("Value.Pointer: expected %s, got %s", tt.want, got)
+			}
+
+			p, err := jsonlite.ParsePointer(tt.pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.String() != tt.pointer {
+				t.Errorf("expected pointer to round trip to %q, got %q", tt.pointer, p.String())
+			}
+			v, err = p.Resolve(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(v.Compact(nil)); got != tt.want {
+				t.Errorf("Pointer.Resolve: expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPointerEscapes(t *testing.T) {
+	p := jsonlite.NewPointer("a/b", "~1", "c")
+	if s := p.String(); s != `/a~1b/~01/c` {
+		t.Errorf("expected /a~1b/~01/c, got %s", s)
+	}
+	q, err := jsonlite.ParsePointer(p.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := q.Tokens()
+	if len(tokens) != 3 || tokens[0] != "a/b" || tokens[1] != "~1" || tokens[2] != "c" {
+		t.Errorf("unexpected tokens: %q", tokens)
+	}
+
+	doc, err := jsonlite.Parse(`{"a/b":{"~1":{"c":true}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := p.Resolve(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Kind() != jsonlite.True {
+		t.Errorf("expected true, got %v", v.Kind())
+	}
+}
+
+func TestPointerErrors(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"items":[{"name":"a"},{"name":"b"}],"count":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pointer string
+		err     error
+		token   int
+	}{
+		{`items`, jsonlite.ErrInvalidPointer, -1},
+		{`/items/~2`, jsonlite.ErrInvalidPointer, -1},
+		{`/items~`, jsonlite.ErrInvalidPointer, -1},
+		{`/missing`, jsonlite.ErrPointerNotFound, 0},
+		{`/items/2`, jsonlite.ErrPointerNotFound, 1},
+		{`/items/-`, jsonlite.ErrPointerNotFound, 1},
+		{`/items/01`, jsonlite.ErrPointerNotFound, 1},
+		{`/items/name`, jsonlite.ErrPointerNotFound, 1},
+		{`/items/99999999999999999999999`, jsonlite.ErrPointerNotFound, 1},
+		{`/items/0/name/x`, jsonlite.ErrPointerNotFound, 3},
+		{`/count/0`, jsonlite.ErrPointerNotFound, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			_, err := doc.Pointer(tt.pointer)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			var ptrErr *jsonlite.PointerError
+			if !errors.As(err, &ptrErr) {
+				t.Fatalf("expected *PointerError, got %T", err)
+			}
+			if ptrErr.Token != tt.token {
+				t.Errorf("expected error at token %d, got %d", tt.token, ptrErr.Token)
+			}
+
+			p, err := jsonlite.ParsePointer(tt.pointer)
+			if tt.err == jsonlite.ErrInvalidPointer {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("ParsePointer: expected %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if _, err := p.Resolve(doc); !errors.Is(err, tt.err) {
+				t.Errorf("Resolve: expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestPointerErrorMessage(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"count":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = doc.Pointer(`/count/x`)
+	if want := `cannot resolve "x" in number value`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error containing %q, got %v", want, err)
+	}
+}
+
+func TestPointerNoAlloc(t *testing.T) {
+	doc, err := jsonlite.Parse(`{"items":[{"name":"a"},{"name":"b"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := jsonlite.ParsePointer("/items/1/name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		if v, _ := doc.Pointer("/items/1/name"); v == nil {
+			t.Fatal("not found")
+		}
+		if v, _ := p.Resolve(doc); v == nil {
+			t.Fatal("not found")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,89 @@
+package jsonlite
+
+// ParseProjection parses JSON data, materializing only the values at the
+// given paths and their ancestors. Each path is a list of object keys, like
+// the arguments of LookupPath; when a value along a path is an array, the rest
+// of the path applies to each of its elements.
+//
+// Fields of objects which are not on any of the paths are skipped with the
+// tokenizer and omitted from the result, which is a normal *Value on which
+// Lookup, Object, and the other methods only see the projected fields. The
+// values at the end of the paths are parsed entirely. An empty path, or no
+// paths at all, selects the whole document.
+//
+// JSON returns the text of the projected fields only: the text of arrays and
+// objects where fields were skipped is rebuilt from their projected values,
+// so it is consistent with Unmarshal, Append and the other consumers of the
+// tree. Skipped values are only checked for balanced brackets, so errors in
+// their content may not be reported.
+func ParseProjection(data string, paths ...[]string) (*Value, error) {
+	p := parsers.Get().(*parser)
+	defer parsers.Put(p)
+	p.configure(&ParseOptions{})
+	p.projection = newProjection(paths)
+	return p.parse(data)
+}
+
+// projection is a tree of the object keys selected by ParseProjection. The
+// keys are searched linearly, since projections usually select few fields.
+type projection struct {
+	keys   []string
+	fields []*projection
+	leaf   bool // the value is selected entirely
+}
+
+// newProjection builds the projection tree of paths, returning nil if the
+// whole document is selected.
+func newProjection(paths [][]string) *projection {
+	if len(paths) == 0 {
+		return nil
+	}
+	root := &projection{}
+	for _, path := range paths {
+		node := root
+		for _, key := range path {
+			if node.leaf {
+				break
+			}
+			child, ok := node.lookup(key)
+			if !ok {
+				child = &projection{}
+				node.keys = append(node.keys, key)
+				node.fields = append(node.fields, child)
+			}
+			node = child
+		}
+		node.leaf, node.keys, node.fields = true, nil, nil
+	}
+	if root.leaf {
+		return nil
+	}
+	return root
+}
+
+// child returns the projection of the value of the field with the given key
+// in an object, and whether the field must be skipped. A nil projection
+// selects the whole value.
+func (pr *projection) child(key string) (next *projection, skip bool) {
+	if pr == nil {
+		return nil, false
+	}
+	child, ok := pr.lookup(key)
+	switch {
+	case !ok:
+		return nil, true
+	case child.leaf:
+		return nil, false
+	default:
+		return child, false
+	}
+}
+
+func (pr *projection) lookup(key string) (*projection, bool) {
+	for i, k := range pr.keys {
+		if k == key {
+			return pr.fields[i], true
+		}
+	}
+	return nil, false
+}
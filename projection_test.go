@@ -0,0 +1,134 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestParseProjection(t *testing.T) {
+	const input = `{
+		"id": 42,
+		"name": "gopher",
+		"tags": ["a", "b"],
+		"profile": {"age": 13, "settings": {"theme": "dark", "lang": "go"}, "bio": "..."},
+		"items": [{"sku": "x1", "price": 10, "meta": {"w": 1}}, {"sku": "x2", "price": 20}],
+		"ignored": {"deeply": [[{"nested": true}]]}
+	}`
+
+	tests := []struct {
+		name  string
+		paths [][]string
+		want  string
+	}{
+		{"no paths", nil, ``},
+		{"scalar fields", [][]string{{"id"}, {"name"}}, `{"id":42,"name":"gopher"}`},
+		{"whole subtree", [][]string{{"profile", "settings"}}, `{"profile":{"settings":{"theme":"dark","lang":"go"}}}`},
+		{"nested fields", [][]string{{"profile", "age"}, {"profile", "settings", "lang"}}, `{"profile":{"age":13,"settings":{"lang":"go"}}}`},
+		{"array elements", [][]string{{"items", "price"}}, `{"items":[{"price":10},{"price":20}]}`},
+		{"whole array", [][]string{{"tags"}}, `{"tags":["a","b"]}`},
+		{"missing field", [][]string{{"missing"}, {"id"}}, `{"id":42}`},
+		{"path through scalar", [][]string{{"id", "x"}}, `{"id":42}`},
+		{"empty path", [][]string{{"id"}, {}}, ``},
+		{"prefix path", [][]string{{"profile", "age"}, {"profile"}}, `{"profile":{"age":13,"settings":{"theme":"dark","lang":"go"},"bio":"..."}}`},
+	}
+
+	full, err := jsonlite.Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := jsonlite.ParseProjection(input, tt.paths...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if want == "" {
+				want = string(full.Compact(nil))
+				if v.Len() != full.Len() {
+					t.Errorf("expected %d fields, got %d", full.Len(), v.Len())
+				}
+			}
+			if got := string(v.Compact(nil)); got != want {
+				t.Errorf("expected %s, got %s", want, got)
+			}
+			// The JSON text only holds the projected fields.
+			parsed, err := jsonlite.Parse(v.JSON())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(parsed.Compact(nil)); got != want {
+				t.Errorf("expected JSON %s, got %s", want, v.JSON())
+			}
+		})
+	}
+}
+
+func TestParseProjectionLookup(t *testing.T) {
+	v, err := jsonlite.ParseProjection(`{"a":{"b":1,"c":2},"d":3}`, []string{"a", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := v.LookupPath("a", "c"); c == nil || c.Int() != 2 {
+		t.Errorf("expected a.c to be 2, got %v", c)
+	}
+	if b := v.LookupPath("a", "b"); b != nil {
+		t.Errorf("expected a.b to be skipped, got %v", b)
+	}
+	if d := v.Lookup("d"); d != nil {
+		t.Errorf("expected d to be skipped, got %v", d)
+	}
+	if n := v.Lookup("a").Len(); n != 1 {
+		t.Errorf("expected a to have 1 field, got %d", n)
+	}
+}
+
+func TestParseProjectionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{`{"a":1,"b":[1,2`, jsonlite.ErrTruncated},
+		{`{"a":1,"b":nul}`, jsonlite.ErrUnexpectedToken},
+		{`{"a":1,"b":2 "c":3}`, jsonlite.ErrUnexpectedToken},
+		{`{"a":[1,2,]}`, jsonlite.ErrUnexpectedToken},
+		{`{"a":1} {}`, jsonlite.ErrTrailingData},
+	}
+
+	for _, tt := range tests {
+		if _, err := jsonlite.ParseProjection(tt.input, []string{"a"}); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.input, tt.err, err)
+		}
+	}
+}
+
+func BenchmarkParseProjection(b *testing.B) {
+	paths := [][]string{{"severity"}, {"httpRequest", "status"}, {"resource", "type"}}
+
+	b.Run("Parse", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(cloudLoggingPayload)))
+		for b.Loop() {
+			v, err := jsonlite.Parse(cloudLoggingPayload)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_ = v.Lookup("severity")
+		}
+	})
+
+	b.Run("ParseProjection", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(cloudLoggingPayload)))
+		for b.Loop() {
+			v, err := jsonlite.ParseProjection(cloudLoggingPayload, paths...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_ = v.Lookup("severity")
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package jsonlite
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// errPushParserClosed is returned when writing to a closed PushParser.
+var errPushParserClosed = errors.New("jsonlite: write to closed push parser")
+
+// PushParser parses a stream of JSON values delivered in chunks of arbitrary
+// size, for example by WebSocket frames or HTTP chunked bodies, when reading
+// from an io.Reader is not possible because it would block.
+//
+// The stream has the same format as the input of a Decoder: values may be
+// separated by whitespace, newlines or record separators. A chunk may end
+// anywhere, including in the middle of a string, a number or an escape
+// sequence; the parser keeps its state until the next chunk completes the
+// value, and each value is passed to the emit function as soon as it is
+// complete. Numbers and literals at the top level are only complete when
+// followed by a delimiter, or when the parser is closed.
+//
+// The input is scanned only once to find the end of values, then each value
+// is parsed from its buffered text. Values passed to emit remain valid after
+// it returns, the memory they reference is never reused by the parser.
+type PushParser struct {
+	emit    func(*Value) error
+	parser  parser
+	scanner valueScanner
+	buf     []byte
+	pos     int // offset of the first byte of buf not consumed yet
+	err     error
+	closed  bool
+	// Position of buf[0] in the stream, used to locate syntax errors.
+	position position
+}
+
+// NewPushParser returns a PushParser calling emit with each value of the
+// stream. Parsing stops if emit returns an error, which is then returned by
+// Write and Close.
+func NewPushParser(emit func(*Value) error) *PushParser {
+	p := &PushParser{emit: emit}
+	p.parser.configure(&ParseOptions{})
+	return p
+}
+
+// Write appends chunk to the input, and emits the values which it completes.
+// The chunk is copied, so it can be reused by the caller after Write returns.
+//
+// Returns the error of emit, or a *SyntaxError if the stream is malformed,
+// after which all calls to Write and Close return the same error.
+func (p *PushParser) Write(chunk []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	if p.closed {
+		return 0, errPushParserClosed
+	}
+	p.append(chunk)
+	return len(chunk), p.parse(false)
+}
+
+// Close signals the end of the stream, emitting the value at the end of the
+// input if it was not complete yet. Returns an error wrapping ErrTruncated if
+// the stream ends in the middle of a value.
+func (p *PushParser) Close() error {
+	if p.closed || p.err != nil {
+		return p.err
+	}
+	p.closed = true
+	return p.parse(true)
+}
+
+// InputOffset returns the offset in the stream up to which the input was
+// consumed, which is after the last value emitted and the whitespace that
+// follows it.
+func (p *PushParser) InputOffset() int64 { return int64(p.position.offset + p.pos) }
+
+// append adds chunk to the buffer. When the buffer is too small, a new one is
+// allocated rather than reusing the memory, which may still be referenced by
+// values emitted earlier.
+func (p *PushParser) append(chunk []byte) {
+	if cap(p.buf)-len(p.buf) < len(chunk) {
+		pending := p.buf[p.pos:]
+		p.position.advance(p.buf[:p.pos])
+		buf := make([]byte, len(pending), max(2*(len(pending)+len(chunk)), defaultDecoderBufferSize))
+		p.buf, p.pos = buf[:copy(buf, pending)], 0
+	}
+	p.buf = append(p.buf, chunk...)
+}
+
+// parse emits the complete values of the buffer, and the last one if eof is
+// set since no more input will be received.
+func (p *PushParser) parse(eof bool) error {
+	for {
+		for p.pos < len(p.buf) {
+			if c := p.buf[p.pos]; !isWhitespace(c) && c != recordSeparator {
+				break
+			}
+			p.pos++
+		}
+		if p.pos == len(p.buf) {
+			return nil
+		}
+		if !p.scanner.scanValue(p.buf[p.pos:], p.parser.maxNesting) && !eof {
+			return nil
+		}
+
+		s := unsafe.String(&p.buf[p.pos], len(p.buf)-p.pos)
+		v, rest, err := p.parser.parseValue(s)
+		if err != nil {
+			p.err = p.position.locate(err, p.buf, p.pos)
+			return p.err
+		}
+		p.pos = len(p.buf) - len(rest)
+		if err := p.emit(&v); err != nil {
+			p.err = err
+			return err
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package jsonlite_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+// pushChunks feeds chunks to a PushParser and returns the compact JSON of the
+// values it emitted, and the error of Write or Close.
+func pushChunks(chunks ...string) ([]string, error) {
+	var values []*jsonlite.Value
+	p := jsonlite.NewPushParser(func(v *jsonlite.Value) error {
+		values = append(values, v)
+		return nil
+	})
+	var err error
+	for _, chunk := range chunks {
+		if _, err = p.Write([]byte(chunk)); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = p.Close()
+	}
+	// Values are compacted last to check that they remain valid.
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = string(v.Compact(nil))
+	}
+	return result, err
+}
+
+func TestPushParser(t *testing.T) {
+	const input = `{"a": "caf\u00e9 \"quoted\"", "b": [1, -2.5e-3, true, null]} 42 "str\\ing" [] {}
+	-0.5 true false null` + "\n\x1e" + `{"nested": {"deep": [[{"x": "\ud83d\ude00"}]]}} 7`
+	want := []string{
+		`{"a":"caf\u00e9 \"quoted\"","b":[1,-2.5e-3,true,null]}`,
+		`42`, `"str\\ing"`, `[]`, `{}`, `-0.5`, `true`, `false`, `null`,
+		`{"nested":{"deep":[[{"x":"\ud83d\ude00"}]]}}`,
+		`7`,
+	}
+
+	check := func(t *testing.T, chunks []string) {
+		t.Helper()
+		got, err := pushChunks(chunks...)
+		if err != nil {
+			t.Fatalf("chunks %q: %v", chunks, err)
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("chunks %q:\nexpected:\n%s\ngot:\n%s", chunks, strings.Join(want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	t.Run("whole", func(t *testing.T) {
+		check(t, []string{input})
+	})
+	t.Run("split", func(t *testing.T) {
+		for i := range len(input) + 1 {
+			check(t, []string{input[:i], input[i:]})
+		}
+	})
+	t.Run("bytes", func(t *testing.T) {
+		chunks := make([]string, len(input))
+		for i := range input {
+			chunks[i] = input[i : i+1]
+		}
+		check(t, chunks)
+	})
+}
+
+func TestPushParserEmitsEarly(t *testing.T) {
+	var values []string
+	p := jsonlite.NewPushParser(func(v *jsonlite.Value) error {
+		values = append(values, v.JSON())
+		return nil
+	})
+	steps := []struct {
+		chunk string
+		want  int
+	}{
+		{`{"a": "}`, 0},
+		{`"}`, 1},
+		{` 12`, 1},
+		{`3`, 1},
+		{`
+`, 2},
+		{`[1, [2`, 2},
+		{`]]`, 3},
+		{`"x`, 3},
+		{`"`, 4},
+		{`fal`, 4},
+	}
+	for _, step := range steps {
+		if _, err := p.Write([]byte(step.chunk)); err != nil {
+			t.Fatal(err)
+		}
+		if len(values) != step.want {
+			t.Fatalf("after writing %q: expected %d values, got %d", step.chunk, step.want, len(values))
+		}
+	}
+	if values[1] != "123" {
+		t.Errorf("expected 123, got %s", values[1])
+	}
+	if err := p.Close(); err == nil || !errors.Is(err, jsonlite.ErrTruncated) {
+		t.Errorf("expected truncated error for fal, got %v", err)
+	}
+}
+
+func TestPushParserErrors(t *testing.T) {
+	t.Run("truncated", func(t *testing.T) {
+		for _, input := range []string{`{"a": 1`, `[1, 2,`, `"abc`, `"abc\`, `{"a"`, `"\u12`} {
+			values, err := pushChunks(input)
+			if len(values) != 0 || !errors.Is(err, jsonlite.ErrTruncated) {
+				t.Errorf("%q: expected truncated error, got %v (%d values)", input, err, len(values))
+			}
+		}
+	})
+
+	t.Run("syntax", func(t *testing.T) {
+		input := "1 2\n[3, 4]\n{\"a\": tru}\n5"
+		values, err := pushChunks(input[:10], input[10:15], input[15:])
+		if len(values) != 3 {
+			t.Errorf("expected 3 values before the error, got %d", len(values))
+		}
+		var e *jsonlite.SyntaxError
+		if !errors.As(err, &e) {
+			t.Fatalf("expected syntax error, got %v", err)
+		}
+		if e.Offset != 17 || e.Line != 3 || e.Column != 7 {
+			t.Errorf("expected error at offset 17, line 3, column 7, got offset %d, line %d, column %d", e.Offset, e.Line, e.Column)
+		}
+	})
+
+	t.Run("emit", func(t *testing.T) {
+		stop := errors.New("stop")
+		n := 0
+		p := jsonlite.NewPushParser(func(v *jsonlite.Value) error {
+			if n++; n == 2 {
+				return stop
+			}
+			return nil
+		})
+		if _, err := p.Write([]byte(`1 2 3 `)); err != stop {
+			t.Errorf("expected emit error, got %v", err)
+		}
+		if _, err := p.Write([]byte(`4 `)); err != stop {
+			t.Errorf("expected emit error to be sticky, got %v", err)
+		}
+		if err := p.Close(); err != stop {
+			t.Errorf("expected emit error from Close, got %v", err)
+		}
+		if n != 2 {
+			t.Errorf("expected 2 calls to emit, got %d", n)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		p := jsonlite.NewPushParser(func(*jsonlite.Value) error { return nil })
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Write([]byte(`1`)); err == nil {
+			t.Error("expected an error writing to a closed parser")
+		}
+	})
+}
+
+func TestPushParserInputOffset(t *testing.T) {
+	p := jsonlite.NewPushParser(func(*jsonlite.Value) error { return nil })
+	input := strings.Repeat(`{"key": "value"}`+"\n", 1000)
+	for i := 0; i < len(input); i += 7 {
+		if _, err := p.Write([]byte(input[i:min(i+7, len(input))])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if off := p.InputOffset(); off != int64(len(input)) {
+		t.Errorf("expected input offset %d, got %d", len(input), off)
+	}
+}
+
+func BenchmarkPushParser(b *testing.B) {
+	input := []byte(strings.Repeat(`{"id": 12345, "name": "some name", "tags": ["a", "b", "c"]}`+"\n", 100))
+	b.SetBytes(int64(len(input)))
+	for b.Loop() {
+		p := jsonlite.NewPushParser(func(*jsonlite.Value) error { return nil })
+		for i := 0; i < len(input); i += 512 {
+			p.Write(input[i:min(i+512, len(input))])
+		}
+		p.Close()
+	}
+}
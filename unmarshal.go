@@ -0,0 +1,137 @@
+package jsonlite
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// Unmarshal decodes the parsed value v into dst, which must be a non-nil
+// pointer. It is the counterpart of DecodeIterator for values which were
+// already parsed, for example to decode a field extracted from a larger
+// document; a nil v leaves dst unchanged.
+//
+// The tree of v is walked by the decoders of DecodeIterator, following the
+// same rules, which are those of encoding/json. Struct fields with the ",string"
+// option hold their value in a JSON string, for example {"id":"42"}, and the
+// option is ignored on fields which are not booleans, numbers or strings.
+// Strings without escape sequences are not copied, they reference the JSON
+// text of v. Values returned by ParseProjection only decode the fields which
+// were selected.
+//
+// A *TypeError is returned when a value does not match the type it is decoded
+// into, with the path of the value relative to v and its offset in the JSON
+// text of v.
+func Unmarshal(v *Value, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("jsonlite: Unmarshal requires a non-nil pointer, got %T", dst)
+	}
+	if v == nil {
+		return nil
+	}
+	src := valueSources.Get().(*valueSource)
+	defer valueSources.Put(src)
+	defer src.reset(nil)
+	src.reset(v)
+	return typeDecoderOf(rv.Type().Elem())(src, rv.Elem())
+}
+
+// valueSources is a pool of the sources used by Unmarshal, which keep their
+// stack between calls.
+var valueSources = sync.Pool{New: func() any { return new(valueSource) }}
+
+// valueSource is the decodeSource walking a tree of values for Unmarshal. The
+// arrays and objects being decoded are kept on a stack, with the index and key
+// of their current element or field.
+type valueSource struct {
+	root  *Value
+	value *Value
+	stack []valueFrame
+}
+
+type valueFrame struct {
+	container *Value
+	index     int
+	key       string
+}
+
+func (s *valueSource) reset(v *Value) {
+	clear(s.stack)
+	s.root, s.value, s.stack = v, v, s.stack[:0]
+}
+
+func (s *valueSource) Kind() Kind { return s.value.Kind() }
+
+func (s *valueSource) number() string { return s.value.json() }
+
+func (s *valueSource) unquote() (string, error) { return Unquote(s.value.json()) }
+
+func (s *valueSource) rawJSON() (string, error) { return s.value.JSON(), nil }
+
+func (s *valueSource) enter() {
+	s.stack = append(s.stack, valueFrame{container: s.value, index: -1})
+}
+
+// leave pops the container at the top of the stack, which becomes the current
+// value again.
+func (s *valueSource) leave() {
+	top := len(s.stack) - 1
+	s.value = s.stack[top].container
+	s.stack[top] = valueFrame{}
+	s.stack = s.stack[:top]
+}
+
+func (s *valueSource) nextElement(i int) (bool, error) {
+	f := &s.stack[len(s.stack)-1]
+	elems := f.container.elements()
+	if i >= len(elems) {
+		s.leave()
+		return false, nil
+	}
+	f.index, s.value = i, &elems[i]
+	return true, nil
+}
+
+func (s *valueSource) nextField(i int) (string, bool, error) {
+	f := &s.stack[len(s.stack)-1]
+	fields := f.container.fields()
+	if i >= len(fields) {
+		s.leave()
+		return "", false, nil
+	}
+	f.index, f.key, s.value = i, fields[i].k, &fields[i].v
+	return f.key, true, nil
+}
+
+func (s *valueSource) Skip() error { return nil }
+
+func (s *valueSource) typeError(t reflect.Type, err error) error {
+	b := []byte{'$'}
+	for _, f := range s.stack {
+		switch {
+		case f.index < 0:
+		case f.container.Kind() == Array:
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(f.index), 10)
+			b = append(b, ']')
+		default:
+			b = appendPathKey(b, f.key)
+		}
+	}
+	return &TypeError{Path: string(b), Offset: s.offset(), Kind: s.value.Kind(), Type: t, Err: err}
+}
+
+// offset returns the byte offset of the current value in the JSON text of the
+// root value, or -1 if the text of the value is not part of it.
+func (s *valueSource) offset() int {
+	root, text := s.root.JSON(), s.value.JSON()
+	off := uintptr(unsafe.Pointer(unsafe.StringData(text))) - uintptr(unsafe.Pointer(unsafe.StringData(root)))
+	if off > uintptr(len(root)) || uintptr(len(text)) > uintptr(len(root))-off {
+		return -1
+	}
+	return int(off) + len(text) - len(strings.TrimLeft(text, " \t\n\r"))
+}
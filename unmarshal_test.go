@@ -0,0 +1,241 @@
+package jsonlite_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/jsonlite"
+)
+
+func TestUnmarshal(t *testing.T) {
+	var want decodeRecord
+	if err := json.Unmarshal([]byte(decodeInput), &want); err != nil {
+		t.Fatal(err)
+	}
+	v, err := jsonlite.Parse(decodeInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got decodeRecord
+	if err := jsonlite.Unmarshal(v, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	// encoding/json decodes numbers in interfaces as float64.
+	if n := got.Any.(map[string]any)["list"].([]any)[0]; n != int64(1) {
+		t.Errorf("expected int64(1), got %T(%v)", n, n)
+	}
+	got.Any, want.Any = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", want, got)
+	}
+	if got.Note != "embedded pointer" || got.Upper != "SHOUT" || !got.When.Equal(time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)) {
+		t.Errorf("unexpected values %+v", got)
+	}
+}
+
+func TestUnmarshalLazy(t *testing.T) {
+	// Values which are not parsed yet are decoded the same way.
+	v, err := jsonlite.ParseWithOptions(decodeInput, jsonlite.ParseOptions{LazyDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, got decodeRecord
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(decodeInput), &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := jsonlite.Unmarshal(v, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", want, got)
+	}
+}
+
+func TestUnmarshalProjection(t *testing.T) {
+	// Only the fields selected by the projection are decoded.
+	v, err := jsonlite.ParseProjection(`{"a": 1, "b": 2, "c": {"d": [3], "e": 4}}`, []string{"a"}, []string{"c", "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := jsonlite.Unmarshal(v, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"a": int64(1), "c": map[string]any{"d": []any{int64(3)}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	var ints map[string]int
+	if err := jsonlite.Unmarshal(v.Lookup("c"), &ints); err == nil {
+		t.Error("expected type error")
+	} else if e := (*jsonlite.TypeError)(nil); !errors.As(err, &e) || e.Path != "$.d" {
+		t.Errorf("expected type error at $.d, got %v", err)
+	}
+}
+
+type unmarshalQuotedRecord struct {
+	ID      int64    `json:"id,string"`
+	Ratio   float64  `json:"ratio,omitempty,string"`
+	Enabled bool     `json:"enabled,string"`
+	Name    string   `json:"name,string"`
+	Count   *uint8   `json:"count,string"`
+	Tags    []string `json:"tags,string"` // the option only applies to scalars
+}
+
+func TestUnmarshalQuoted(t *testing.T) {
+	input := `{"id": "-42", "ratio": "0.5", "enabled": "true", "name": "\"x\"", "count": "7", "tags": ["a"]}`
+	var want unmarshalQuotedRecord
+	if err := json.Unmarshal([]byte(input), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	v, _ := jsonlite.Parse(input)
+	var got unmarshalQuotedRecord
+	if err := jsonlite.Unmarshal(v, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	got = unmarshalQuotedRecord{}
+	if err := jsonlite.DecodeIterator(jsonlite.Iterate(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeIterator: expected %+v, got %+v", want, got)
+	}
+
+	for _, input := range []string{
+		`{"id": 42}`,
+		`{"id": "x"}`,
+		`{"id": "\"42\""}`,
+		`{"name": "x"}`,
+		`{"enabled": "1"}`,
+		`{"count": "300"}`,
+	} {
+		v, _ := jsonlite.Parse(input)
+		var got unmarshalQuotedRecord
+		if err := jsonlite.Unmarshal(v, &got); !errors.Is(err, jsonlite.ErrTypeMismatch) {
+			t.Errorf("%s: expected type mismatch, got %v", input, err)
+		}
+		if err := jsonlite.DecodeIterator(jsonlite.Iterate(input), &got); !errors.Is(err, jsonlite.ErrTypeMismatch) {
+			t.Errorf("%s: DecodeIterator: expected type mismatch, got %v", input, err)
+		}
+	}
+}
+
+func TestUnmarshalReuse(t *testing.T) {
+	n := 1
+	v := struct {
+		List    []int
+		Map     map[string]int
+		Array   [3]int
+		Pointer *int
+		Keep    string
+	}{
+		List:    []int{1, 2, 3},
+		Map:     map[string]int{"a": 1},
+		Array:   [3]int{1, 2, 3},
+		Pointer: &n,
+		Keep:    "kept",
+	}
+	value, _ := jsonlite.Parse(`{"List": [4], "Map": {"b": 2}, "Array": [5], "Pointer": 6, "Keep": null}`)
+	if err := jsonlite.Unmarshal(value, &v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v.List, []int{4}) || !reflect.DeepEqual(v.Map, map[string]int{"a": 1, "b": 2}) ||
+		v.Array != [3]int{5, 0, 0} || v.Pointer != &n || n != 6 || v.Keep != "kept" {
+		t.Errorf("unexpected values %+v", v)
+	}
+
+	if err := jsonlite.Unmarshal(nil, &v); err != nil {
+		t.Errorf("expected nil value to be ignored, got %v", err)
+	}
+	if err := jsonlite.Unmarshal(value, v); err == nil {
+		t.Error("expected error decoding into a non-pointer")
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type inner struct {
+		Values []int8 `json:"values"`
+	}
+	type outer struct {
+		Items []inner        `json:"items"`
+		Name  string         `json:"name"`
+		Iface fmtStringer    `json:"iface"`
+		Map   map[string]int `json:"map"`
+		ByID  map[int]bool   `json:"by_id"`
+		Pair  [2]bool        `json:"pair"`
+	}
+
+	tests := []struct {
+		input  string
+		path   string
+		offset int
+	}{
+		{`{"items": [{"values": [1, 200]}]}`, `$.items[0].values[1]`, 26},
+		{`{"items": [{}, {"values": "x"}]}`, `$.items[1].values`, 26},
+		{`{"name": 1}`, `$.name`, 9},
+		{`{"map": {"a b": true}}`, `$.map['a b']`, 16},
+		{`{"by_id": {"x": true}}`, `$.by_id.x`, 16},
+		{`{"pair": [true, 0]}`, `$.pair[1]`, 16},
+		{`{"iface": "x"}`, `$.iface`, 10},
+		{`[]`, `$`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, err := jsonlite.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var x outer
+			err = jsonlite.Unmarshal(v, &x)
+			var e *jsonlite.TypeError
+			if !errors.As(err, &e) || !errors.Is(err, jsonlite.ErrTypeMismatch) {
+				t.Fatalf("expected type error, got %v", err)
+			}
+			if e.Path != tt.path {
+				t.Errorf("expected error at %s, got %s", tt.path, e.Path)
+			}
+			if e.Offset != tt.offset {
+				t.Errorf("expected error at offset %d, got %d", tt.offset, e.Offset)
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	type item struct {
+		ID    int64    `json:"id"`
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Price float64  `json:"price"`
+	}
+	type payload struct {
+		Items []item `json:"items"`
+		Total int    `json:"total"`
+	}
+	input := `{"items": [` + strings.Repeat(`{"id": 12345, "name": "some name", "tags": ["a", "b"], "price": 9.99, "extra": {"x": 1}}, `, 99) +
+		`{"id": 1, "name": "last", "tags": [], "price": 0}], "total": 100}`
+	v, err := jsonlite.Parse(input)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(input)))
+
+	var x payload
+	for b.Loop() {
+		if err := jsonlite.Unmarshal(v, &x); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -20,7 +20,7 @@ const (
 // When the string contains no escape sequences, returns a zero-copy substring.
 func Unquote(s string) (string, error) {
 	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
-		return "", fmt.Errorf("invalid quoted string: %s", s)
+		return "", fmt.Errorf("%w: invalid quoted string: %s", ErrUnexpectedToken, s)
 	}
 	s = s[1 : len(s)-1]
 	// Fast path: check if string needs unescaping (has backslash or control chars)
@@ -35,7 +35,7 @@ func Unquote(s string) (string, error) {
 // Returns an error if the string is not properly quoted or contains invalid escapes.
 func AppendUnquote(b []byte, s string) ([]byte, error) {
 	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
-		return b, fmt.Errorf("invalid quoted string: %s", s)
+		return b, fmt.Errorf("%w: invalid quoted string: %s", ErrUnexpectedToken, s)
 	}
 	s = s[1 : len(s)-1]
 	// Fast path: check if string needs unescaping
@@ -128,10 +128,10 @@ func unquote(b []byte, s string) ([]byte, error) {
 		b = append(b, s[:i]...)
 		c := s[i]
 		if c < 0x20 {
-			return b, fmt.Errorf("invalid control character in string")
+			return b, fmt.Errorf("%w: control character in string", ErrInvalidEscape)
 		}
 		if i+1 >= len(s) {
-			return b, fmt.Errorf("invalid escape sequence at end of string")
+			return b, fmt.Errorf("%w: backslash at end of string", ErrInvalidEscape)
 		}
 
 		switch c := s[i+1]; c {
@@ -155,29 +155,29 @@ func unquote(b []byte, s string) ([]byte, error) {
 			s = s[i+2:]
 		case 'u':
 			if i+6 > len(s) {
-				return b, fmt.Errorf("invalid unicode escape sequence")
+				return b, fmt.Errorf("%w: malformed unicode escape sequence", ErrInvalidEscape)
 			}
 			r1, ok := parseHex4(s[i+2 : i+6])
 			if !ok {
-				return b, fmt.Errorf("invalid unicode escape sequence")
+				return b, fmt.Errorf("%w: malformed unicode escape sequence", ErrInvalidEscape)
 			}
 
 			// Check for UTF-16 surrogate pair
 			if utf16.IsSurrogate(r1) {
 				// Low surrogate without high surrogate is an error
 				if r1 >= lowSurrogateMin {
-					return b, fmt.Errorf("invalid surrogate pair: unexpected low surrogate")
+					return b, fmt.Errorf("%w: unexpected low surrogate", ErrInvalidEscape)
 				}
 				// High surrogate, look for low surrogate
 				if i+12 > len(s) || s[i+6] != '\\' || s[i+7] != 'u' {
-					return b, fmt.Errorf("invalid surrogate pair: missing low surrogate")
+					return b, fmt.Errorf("%w: missing low surrogate", ErrInvalidEscape)
 				}
 				r2, ok := parseHex4(s[i+8 : i+12])
 				if !ok {
-					return b, fmt.Errorf("invalid unicode escape sequence in surrogate pair")
+					return b, fmt.Errorf("%w: malformed low surrogate", ErrInvalidEscape)
 				}
 				if r2 < lowSurrogateMin || r2 > lowSurrogateMax {
-					return b, fmt.Errorf("invalid surrogate pair: low surrogate out of range")
+					return b, fmt.Errorf("%w: low surrogate out of range", ErrInvalidEscape)
 				}
 				// Decode the surrogate pair
 				b = utf8.AppendRune(b, utf16.DecodeRune(r1, r2))
@@ -187,7 +187,7 @@ func unquote(b []byte, s string) ([]byte, error) {
 				s = s[i+6:]
 			}
 		default:
-			return b, fmt.Errorf("invalid escape character: %q", c)
+			return b, fmt.Errorf("%w: unknown escape character %q", ErrInvalidEscape, c)
 		}
 	}
 	return b, nil
@@ -3,43 +3,180 @@ package jsonlite
 // Valid reports whether json is a valid JSON string.
 // This is similar to encoding/json.Valid but uses the jsonlite tokenizer
 // for efficient zero-allocation validation.
-func Valid(json string) bool {
+func Valid(json string) bool { return Validate(json) == nil }
+
+// Validate checks that json is a valid JSON string. It returns nil if the
+// input is valid, or a *SyntaxError describing the first problem found.
+// No memory is allocated when the input is valid, unless it is nested more
+// than 256 levels deep.
+//
+// Arrays and objects may be nested up to DefaultMaxNesting levels, deeper
+// input is rejected with an error wrapping ErrDepthExceeded.
+func Validate(json string) error {
 	tok := Tokenize(json)
-	if !valid(tok) {
-		return false
+	if err := valid(tok, DefaultMaxNesting); err != nil {
+		return locate(json, 0, err)
 	}
 	// Ensure no trailing content after root value
-	_, ok := tok.Next()
-	return !ok
+	if token, ok := tok.Next(); ok {
+		err := syntaxErrorf(token, tok.json, ErrTrailingData, "unexpected token after root value: %q", token)
+		return locate(json, len(json)-len(token)-len(tok.json), err)
+	}
+	return nil
 }
 
-// valid validates a single JSON value and returns true if valid.
-func valid(tok *Tokenizer) bool {
+// valid validates a single JSON value and returns nil if valid.
+func valid(tok *Tokenizer, maxNesting int) error {
 	token, ok := tok.Next()
 	if !ok {
-		return false
+		return syntaxErrorf("", "", ErrTruncated, "unexpected end of input")
+	}
+	return validTokens(tokenStream{tok: tok}, token, maxNesting)
+}
+
+// tokenStream is the source of the tokens validated by validTokens. Tokens
+// are read from tok, or from it when it is set, in which case tok must be
+// the tokenizer of the iterator; the iterator refills its window as tokens
+// are read, so values larger than the window can be validated.
+type tokenStream struct {
+	tok *Tokenizer
+	it  *Iterator
+}
+
+func (s tokenStream) next() (string, bool) {
+	if s.it != nil {
+		return s.it.next()
 	}
-	return validToken(tok, token)
+	return s.tok.Next()
 }
 
-// validToken validates a token and any nested structure it may contain.
-func validToken(tok *Tokenizer, token string) bool {
+// validTokens validates the JSON value starting with token, reading its other
+// tokens from s, and returns nil if valid. Nested arrays and objects are
+// tracked on an explicit stack of their closing brackets, which is kept on
+// the goroutine stack for the first levels.
+func validTokens(s tokenStream, token string, maxNesting int) error {
+	var buf [256]byte
+	stack := buf[:0]
+	tok := s.tok
+	var ok bool
+
+	for {
+		switch token[0] {
+		case '[', '{':
+			if len(stack) >= maxNesting {
+				return depthError(token, tok.json, maxNesting)
+			}
+			closer, kind := byte(']'), Array
+			if token[0] == '{' {
+				closer, kind = '}', Object
+			}
+			if token, ok = s.next(); !ok {
+				return truncatedError(kind)
+			}
+			if token[0] == closer {
+				break
+			}
+			stack = append(stack, closer)
+			if kind == Object {
+				var err error
+				if token, err = validField(s, token); err != nil {
+					return err
+				}
+			}
+			continue
+		default:
+			if err := validScalar(tok, token); err != nil {
+				return err
+			}
+		}
+
+		// Consume the closing brackets following the value, until reaching
+		// the next element or field of an enclosing container.
+		for {
+			if len(stack) == 0 {
+				return nil
+			}
+			closer, kind := stack[len(stack)-1], Array
+			if closer == '}' {
+				kind = Object
+			}
+			if token, ok = s.next(); !ok {
+				return truncatedError(kind)
+			}
+			if token[0] == closer {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			if token != "," {
+				return syntaxErrorf(token, tok.json, ErrUnexpectedToken, "expected ',' or '%c', got %q", closer, token)
+			}
+			if token, ok = s.next(); !ok {
+				return truncatedError(kind)
+			}
+			if token[0] == closer {
+				// Trailing comma is not valid JSON
+				return syntaxErrorf(token, tok.json, ErrUnexpectedToken, "unexpected '%c' after ','", closer)
+			}
+			if kind == Object {
+				var err error
+				if token, err = validField(s, token); err != nil {
+					return err
+				}
+			}
+			break
+		}
+	}
+}
+
+// validField validates the key of an object field in token and the colon
+// after it, and returns the first token of the field value.
+func validField(s tokenStream, token string) (string, error) {
+	tok := s.tok
+	// Expect string key
+	if token[0] != '"' || !validString(token) {
+		return "", keyError(token, tok.json, nil)
+	}
+	// Expect colon
+	token, ok := s.next()
+	if !ok {
+		return "", truncatedError(Object)
+	}
+	if token != ":" {
+		return "", syntaxErrorf(token, tok.json, ErrUnexpectedToken, "expected ':', got %q", token)
+	}
+	// Expect value
+	token, ok = s.next()
+	if !ok {
+		return "", syntaxErrorf("", "", ErrTruncated, "unexpected end of input")
+	}
+	return token, nil
+}
+
+// validScalar validates a token which is not the start of an array or object.
+func validScalar(tok *Tokenizer, token string) error {
 	switch token[0] {
 	case 'n':
-		return token == "null"
+		if token != "null" {
+			return tokenError(token, tok.json)
+		}
 	case 't':
-		return token == "true"
+		if token != "true" {
+			return tokenError(token, tok.json)
+		}
 	case 'f':
-		return token == "false"
+		if token != "false" {
+			return tokenError(token, tok.json)
+		}
 	case '"':
-		return validString(token)
-	case '[':
-		return validArray(tok)
-	case '{':
-		return validObject(tok)
+		if !validString(token) {
+			return tokenError(token, tok.json)
+		}
 	default:
-		return validNumber(token)
+		if !validNumber(token) {
+			return tokenError(token, tok.json)
+		}
 	}
+	return nil
 }
 
 // validString checks if a token is a valid JSON string.
@@ -101,89 +238,6 @@ func isHexDigit(c byte) bool {
 	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
 }
 
-// validArray validates a JSON array starting after the '[' token.
-func validArray(tok *Tokenizer) bool {
-	// Check for empty array
-	token, ok := tok.Next()
-	if !ok {
-		return false
-	}
-	if token == "]" {
-		return true
-	}
-
-	// Parse first element
-	if !validToken(tok, token) {
-		return false
-	}
-
-	// Parse remaining elements
-	for {
-		token, ok = tok.Next()
-		if !ok {
-			return false
-		}
-		if token == "]" {
-			return true
-		}
-		if token != "," {
-			return false
-		}
-		// Expect value after comma
-		token, ok = tok.Next()
-		if !ok {
-			return false
-		}
-		if token == "]" {
-			// Trailing comma is not valid JSON
-			return false
-		}
-		if !validToken(tok, token) {
-			return false
-		}
-	}
-}
-
-// validObject validates a JSON object starting after the '{' token.
-func validObject(tok *Tokenizer) bool {
-	for i := 0; ; i++ {
-		token, ok := tok.Next()
-		if !ok {
-			return false
-		}
-		if token == "}" {
-			return true // Empty object or end of object
-		}
-		if i > 0 {
-			// After first field, expect comma then key
-			if token != "," {
-				return false
-			}
-			token, ok = tok.Next()
-			if !ok {
-				return false
-			}
-			if token == "}" {
-				// Trailing comma is not valid JSON
-				return false
-			}
-		}
-		// Expect string key
-		if len(token) == 0 || token[0] != '"' || !validString(token) {
-			return false
-		}
-		// Expect colon
-		token, ok = tok.Next()
-		if !ok || token != ":" {
-			return false
-		}
-		// Expect value
-		if !valid(tok) {
-			return false
-		}
-	}
-}
-
 // validNumber checks if a string is a valid JSON number.
 // JSON numbers: -?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?
 func validNumber(s string) bool {
@@ -6,6 +6,7 @@ import (
 	"hash/maphash"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"unsafe"
 )
 
@@ -49,10 +50,11 @@ const (
 // Value represents a JSON value of any type.
 //
 // Value instances as immutable, they can be safely accessed from multiple
-// goroutines.
+// goroutines. Methods such as With and Without return modified copies which
+// share the unchanged values with the original.
 //
 // The zero-value of Value is invalid, all Value instances must be acquired
-// form Parse or from an Iterator.
+// from Parse, from an Iterator, or be constructed with a Builder or ValueOf.
 type Value struct {
 	p unsafe.Pointer
 	n uintptr
@@ -156,10 +158,13 @@ func (v *Value) String() string {
 	case Number, True, False:
 		return v.json()
 	case Array:
+		if v.unparsed() {
+			return v.lazy().json
+		}
 		return (*Value)(v.p).json()
 	default:
 		if v.unparsed() {
-			return v.json()
+			return v.lazy().json
 		}
 		return (*field)(v.p).v.json()
 	}
@@ -171,10 +176,13 @@ func (v *Value) JSON() string {
 	case String, Number, Null, True, False:
 		return v.json()
 	case Array:
+		if v.unparsed() {
+			return v.lazy().json
+		}
 		return (*Value)(v.p).json()
 	default:
 		if v.unparsed() {
-			return v.json()
+			return v.lazy().json
 		}
 		return (*field)(v.p).v.json()
 	}
@@ -186,11 +194,7 @@ func (v *Value) Array(yield func(*Value) bool) {
 	if v.Kind() != Array {
 		panic("jsonlite: Array called on non-array value")
 	}
-	parsed := v
-	if v.unparsed() {
-		parsed = v.parse()
-	}
-	elems := unsafe.Slice((*Value)(parsed.p), parsed.len())[1:]
+	elems := v.elements()
 	for i := range elems {
 		if !yield(&elems[i]) {
 			return
@@ -204,11 +208,7 @@ func (v *Value) Object(yield func(string, *Value) bool) {
 	if v.Kind() != Object {
 		panic("jsonlite: Object called on non-object value")
 	}
-	parsed := v
-	if v.unparsed() {
-		parsed = v.parse()
-	}
-	fields := unsafe.Slice((*field)(parsed.p), parsed.len())[1:]
+	fields := v.fields()
 	for i := range fields {
 		if !yield(fields[i].k, &fields[i].v) {
 			return
@@ -223,16 +223,21 @@ func (v *Value) Lookup(k string) *Value {
 	if v.Kind() != Object {
 		panic("jsonlite: Lookup called on non-object value")
 	}
+	return v.lookup(k, byte(maphash.String(hashseed, k)))
+}
+
+// lookup returns the value of the first field of the object v with key k,
+// where hash is the hash byte of k.
+func (v *Value) lookup(k string, hash byte) *Value {
 	parsed := v
 	if v.unparsed() {
 		parsed = v.parse()
 	}
 	fields := unsafe.Slice((*field)(parsed.p), parsed.len())
 	hashes := fields[0].k
-	refkey := byte(maphash.String(hashseed, k))
 	offset := 0
 	for {
-		i := strings.IndexByte(hashes[offset:], refkey)
+		i := strings.IndexByte(hashes[offset:], hash)
 		if i < 0 {
 			return nil
 		}
@@ -265,12 +270,7 @@ func (v *Value) Index(i int) *Value {
 	if v.Kind() != Array {
 		panic("jsonlite: Index called on non-array value")
 	}
-	parsed := v
-	if v.unparsed() {
-		parsed = v.parse()
-	}
-	elems := unsafe.Slice((*Value)(parsed.p), parsed.len())[1:]
-	return &elems[i]
+	return &v.elements()[i]
 }
 
 // NumberType returns the classification of the number (int, uint, or float).
@@ -303,14 +303,53 @@ func (v *Value) unparsed() bool {
 	return (v.n & unparsedBit) != 0
 }
 
+func (v *Value) lazy() *lazyValue {
+	return (*lazyValue)(v.p)
+}
+
+// elements returns the elements of the array v, parsing it if needed.
+func (v *Value) elements() []Value {
+	parsed := v
+	if v.unparsed() {
+		parsed = v.parse()
+	}
+	return unsafe.Slice((*Value)(parsed.p), parsed.len())[1:]
+}
+
+// fields returns the fields of the object v, parsing it if needed.
+func (v *Value) fields() []field {
+	parsed := v
+	if v.unparsed() {
+		parsed = v.parse()
+	}
+	return unsafe.Slice((*field)(parsed.p), parsed.len())[1:]
+}
+
+// parse returns the parsed tree of an unparsed value. The tree is parsed on
+// first access and cached, concurrent calls may parse the value more than once
+// but all return the same tree.
 func (v *Value) parse() *Value {
-	parsed, err := Parse(v.JSON())
+	lazy := v.lazy()
+	if parsed := lazy.parsed.Load(); parsed != nil {
+		return parsed
+	}
+	parsed, err := Parse(lazy.json)
 	if err != nil {
 		panic(fmt.Errorf("jsonlite: lazy parse failed: %w", err))
 	}
+	if !lazy.parsed.CompareAndSwap(nil, parsed) {
+		return lazy.parsed.Load()
+	}
 	return parsed
 }
 
+// lazyValue holds the JSON of an array or object stored unparsed, and the
+// parsed tree once it has been accessed.
+type lazyValue struct {
+	json   string
+	parsed atomic.Pointer[Value]
+}
+
 // NumberType represents the classification of a JSON number.
 type NumberType int
 
@@ -375,10 +414,10 @@ func makeObjectValue(fields []field) Value {
 	}
 }
 
-func makeUnparsedObjectValue(json string) Value {
+func makeUnparsedValue(k Kind, lazy *lazyValue) Value {
 	return Value{
-		p: unsafe.Pointer(unsafe.StringData(json)),
-		n: (uintptr(Object) << kindShift) | uintptr(len(json)) | unparsedBit,
+		p: unsafe.Pointer(lazy),
+		n: (uintptr(k) << kindShift) | uintptr(len(lazy.json)) | unparsedBit,
 	}
 }
 
@@ -391,7 +430,8 @@ func (v *Value) Append(buf []byte) []byte { return append(buf, v.JSON()...) }
 // JSON bytes.
 //
 // For unparsed (lazy) arrays/objects, this returns only the current memory
-// usage (struct + JSON), not what it would be after parsing.
+// usage: the parsed tree is included once it has been cached by an access to
+// the value, not before.
 func (v *Value) Size() int64 {
 	return int64(unsafe.Sizeof(*v)) + v.size() + int64(len(v.JSON()))
 }
@@ -400,7 +440,7 @@ func (v *Value) size() int64 {
 	switch v.Kind() {
 	case Array:
 		if v.unparsed() {
-			return 0
+			return v.lazy().size()
 		}
 		values := unsafe.Slice((*Value)(v.p), v.len())
 		n := int64(len(values)) * int64(unsafe.Sizeof(Value{}))
@@ -410,7 +450,7 @@ func (v *Value) size() int64 {
 		return n
 	case Object:
 		if v.unparsed() {
-			return 0
+			return v.lazy().size()
 		}
 		fields := unsafe.Slice((*field)(v.p), v.len())
 		n := int64(len(fields)) * int64(unsafe.Sizeof(field{}))
@@ -424,6 +464,15 @@ func (v *Value) size() int64 {
 	}
 }
 
+func (lazy *lazyValue) size() int64 {
+	n := int64(unsafe.Sizeof(*lazy))
+	if parsed := lazy.parsed.Load(); parsed != nil {
+		// The parsed tree references the same JSON bytes
+		n += int64(unsafe.Sizeof(*parsed)) + parsed.size()
+	}
+	return n
+}
+
 // Compact appends a compacted JSON representation of the value to buf by recursively
 // reconstructing it from the parsed structure. Unlike Append, this method does not
 // use cached JSON and always regenerates the output.
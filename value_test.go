@@ -3,6 +3,7 @@ package jsonlite_test
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/parquet-go/jsonlite"
@@ -1483,6 +1484,57 @@ func TestSizeIncludesJSON(t *testing.T) {
 	}
 }
 
+func TestUnparsedObjectCached(t *testing.T) {
+	val, err := jsonlite.ParseWithOptions(`{"a":{"b":{"c":1},"d":[1,2]}}`, jsonlite.ParseOptions{LazyDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := val.Lookup("a")
+	sizeBefore := val.Size()
+
+	b := a.Lookup("b")
+	if b == nil {
+		t.Fatal("expected to find 'b'")
+	}
+	if again := a.Lookup("b"); again != b {
+		t.Error("expected lookups on an unparsed object to return values of the cached tree")
+	}
+	sizeAfter := val.Size()
+	if sizeAfter <= sizeBefore {
+		t.Errorf("expected Size() to include the cached tree: %d -> %d", sizeBefore, sizeAfter)
+	}
+	if again := val.Size(); again != sizeAfter {
+		t.Errorf("expected Size() to be stable once cached: %d -> %d", sizeAfter, again)
+	}
+}
+
+func TestUnparsedObjectConcurrentAccess(t *testing.T) {
+	val, err := jsonlite.ParseWithOptions(`{"a":{"b":{"c":1},"d":[1,2]}}`, jsonlite.ParseOptions{LazyDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := val.Lookup("a")
+
+	var wg sync.WaitGroup
+	results := make([]*jsonlite.Value, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = a.Lookup("d")
+		}()
+	}
+	wg.Wait()
+	for i, r := range results {
+		if r != results[0] {
+			t.Errorf("goroutine %d observed a different tree", i)
+		}
+		if r.Len() != 2 {
+			t.Errorf("goroutine %d: expected 2 elements, got %d", i, r.Len())
+		}
+	}
+}
+
 func TestSizeUnparsedObject(t *testing.T) {
 	// When using ParseMaxDepth, nested objects may be unparsed
 	// Size() should return the current memory usage (not trigger parsing)